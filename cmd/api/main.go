@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"openapphub/internal/config"
+	"openapphub/internal/health"
 	"openapphub/internal/middleware"
 	"openapphub/internal/server"
+	"openapphub/internal/util"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -18,27 +27,93 @@ import (
 // @host            localhost:3000
 // @BasePath        /api/v1
 func main() {
-	// 从配置文件读取配置
-	config.Init()
+	// 从配置文件和环境变量读取配置, 配置错误时列出所有错误后退出
+	conf, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误:\n%v\n", err)
+		os.Exit(1)
+	}
+	// 跨域等中间件的默认配置依赖运行模式, 需要在 Init 之前设置
+	gin.SetMode(conf.Server.Mode)
+	config.Init(conf)
+	logger := middleware.GetZapLogger()
 
-	// 初始化日志
-	// middleware.InitLogger()
+	// 收到 SIGTERM 或 SIGINT 时开始退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// 收到 SIGHUP 或配置文件变化时热更新配置
+	go config.Watch(ctx, 5*time.Second)
 
-	// 装载路由
-	gin.SetMode(os.Getenv("GIN_MODE"))
 	// 禁用
 	gin.DefaultWriter = io.Discard
 
-	r := server.NewRouter()
+	// 装载路由
+	r := server.NewRouter(conf)
+	srv := server.NewHTTPServer(conf, r)
 
-	middleware.GetZapLogger().Info("服务器正在启动")
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000" // 默认端口
+	// 单独监听的指标服务
+	ms := server.NewMetricsServer(conf)
+	if ms != nil {
+		go func() {
+			logger.Info("指标服务正在启动", zap.String("addr", ms.Addr))
+			if err := ms.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("指标服务启动失败", zap.Error(err))
+			}
+		}()
 	}
+
+	logger.Info("服务器正在启动")
+	port := conf.Server.Port
 	fmt.Printf("服务器正在启动，监听端口：%s\n", port)
 
-	if err := r.Run(":" + port); err != nil {
-		middleware.GetZapLogger().Error("服务器启动失败", zap.Error(err))
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Fatal("服务器启动失败", zap.Error(err))
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(middleware.ProxyListener(listener))
+	}()
+
+	select {
+	case err := <-serveErr:
+		logger.Error("服务器启动失败", zap.Error(err))
+	case <-ctx.Done():
+		// 再次收到信号时立即退出
+		stop()
+		shutdown(srv, ms, time.Duration(conf.Server.ShutdownDelay), time.Duration(conf.Server.ShutdownTimeout))
+	}
+
+	// 关闭审计日志、链路追踪、Redis、数据库和日志
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := config.Close(closeCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "退出时出现错误:\n%v\n", err)
+	}
+}
+
+// shutdown 先让就绪检查失败, 等待 delay 让负载均衡摘除实例, 再停止接收请求,
+// 最多等待 timeout 让处理中的请求和写入缓存等后台任务完成
+func shutdown(srv, ms *http.Server, delay, timeout time.Duration) {
+	logger := middleware.GetZapLogger()
+	health.SetDraining()
+	logger.Info("收到退出信号, 就绪检查已标记为失败", zap.Duration("delay", delay))
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	logger.Info("停止接收请求, 等待处理中的请求完成", zap.Duration("timeout", timeout))
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("处理中的请求未能在超时前完成, 强制关闭连接", zap.Error(err))
+		srv.Close()
+	}
+	if ms != nil {
+		if err := ms.Shutdown(ctx); err != nil {
+			ms.Close()
+		}
+	}
+	if err := util.WaitBackground(ctx); err != nil {
+		logger.Warn("后台任务未能在超时前完成", zap.Error(err))
 	}
 }
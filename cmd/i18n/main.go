@@ -0,0 +1,54 @@
+// i18n 扫描代码中 i18n.T、i18n.Translate 使用的 key, 按语言报告消息目录中缺失和没有使用的 key.
+// 有缺失的 key 时退出码为 1
+//
+//	go run ./cmd/i18n -src . -locales internal/config/locales
+//	go generate ./internal/config
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"openapphub/pkg/i18n"
+)
+
+func main() {
+	src := flag.String("src", ".", "directory to scan for Go source")
+	dir := flag.String("locales", "internal/config/locales", "directory of locale files")
+	unused := flag.Bool("unused", true, "report keys that are not used in code")
+	flag.Parse()
+
+	bundle, err := i18n.ReadDir(*dir)
+	if err != nil {
+		fail(err)
+	}
+	usage, err := i18n.Extract(*src)
+	if err != nil {
+		fail(err)
+	}
+
+	missing := false
+	for _, report := range usage.Check(bundle) {
+		for _, key := range report.Missing {
+			missing = true
+			fmt.Printf("%s: missing %s (%v)\n", report.Locale, key, usage.Keys[key][0])
+		}
+		if *unused {
+			for _, key := range report.Unused {
+				fmt.Printf("%s: unused %s\n", report.Locale, key)
+			}
+		}
+	}
+	for _, pos := range usage.Dynamic {
+		fmt.Printf("warning: key cannot be determined statically (%v)\n", pos)
+	}
+	if missing {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,60 @@
+// secrets 生成本地加密密钥, 以及加密写进配置文件或环境变量的密钥
+//
+//	go run ./cmd/secrets keygen
+//	echo -n "my-jwt-secret" | SECRETS_KEY=... go run ./cmd/secrets encrypt
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"openapphub/pkg/secrets"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "keygen":
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(key)
+	case "encrypt":
+		key := os.Getenv("SECRETS_KEY")
+		if key == "" && os.Getenv("SECRETS_KEY_FILE") != "" {
+			var err error
+			if key, err = secrets.ReadFile(os.Getenv("SECRETS_KEY_FILE")); err != nil {
+				fail(err)
+			}
+		}
+		if key == "" {
+			fail(fmt.Errorf("SECRETS_KEY or SECRETS_KEY_FILE is required"))
+		}
+		plaintext, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fail(err)
+		}
+		value, err := secrets.Encrypt(key, strings.TrimRight(string(plaintext), "\r\n"))
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(value)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: secrets keygen | secrets encrypt < plaintext")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
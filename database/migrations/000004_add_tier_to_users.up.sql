@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN tier VARCHAR(50) NOT NULL DEFAULT 'free' AFTER status;
@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN ip;
+ALTER TABLE jwt_tokens DROP COLUMN ip;
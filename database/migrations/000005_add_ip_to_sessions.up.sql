@@ -0,0 +1,2 @@
+ALTER TABLE jwt_tokens ADD COLUMN ip VARCHAR(45) AFTER device_info;
+ALTER TABLE sessions ADD COLUMN ip VARCHAR(45) AFTER device_info;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    occurred_at DATETIME(6) NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    actor_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    target_type VARCHAR(32) NOT NULL DEFAULT '',
+    target_id VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    outcome VARCHAR(16) NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    details TEXT,
+    prev_hash CHAR(64) NOT NULL DEFAULT '',
+    hash CHAR(64) NOT NULL,
+    UNIQUE KEY idx_audit_events_hash (hash),
+    UNIQUE KEY idx_audit_events_prev_hash (prev_hash),
+    KEY idx_audit_events_occurred_at (occurred_at),
+    KEY idx_audit_events_action (action),
+    KEY idx_audit_events_actor_id (actor_id),
+    KEY idx_audit_events_target (target_type, target_id)
+);
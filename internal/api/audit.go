@@ -0,0 +1,144 @@
+package api
+
+import (
+	"openapphub/internal/audit"
+	"openapphub/internal/model"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAuditLimit 每页默认返回的审计记录数
+const defaultAuditLimit = 50
+
+// AuditEventsInput 查询审计记录的条件, 按时间倒序返回, 用上一页的 next_before 作为 before 翻页
+type AuditEventsInput struct {
+	ActorID uint      `form:"actor_id"`
+	UserID  uint      `form:"user_id"` // 该用户执行的操作和以该用户为目标的操作
+	Action  string    `form:"action"`
+	Outcome string    `form:"outcome" binding:"omitempty,oneof=success failure error"`
+	Since   time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until   time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Before  uint      `form:"before"`
+	Limit   int       `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+func (input AuditEventsInput) filter() model.AuditFilter {
+	if input.Limit == 0 {
+		input.Limit = defaultAuditLimit
+	}
+	return model.AuditFilter{
+		ActorID: input.ActorID,
+		UserID:  input.UserID,
+		Action:  input.Action,
+		Outcome: input.Outcome,
+		Since:   input.Since,
+		Until:   input.Until,
+		Before:  input.Before,
+		Limit:   input.Limit,
+	}
+}
+
+// ListAuditEvents godoc
+// @Summary List audit events
+// @Description Security-relevant events (logins, logouts, device revocations, token refreshes, cache clears), newest first
+// @Tags audit
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param actor_id query int false "User who performed the action"
+// @Param user_id query int false "Events performed by or targeting this user"
+// @Param action query string false "Action, e.g. user.login"
+// @Param outcome query string false "success, failure or error"
+// @Param since query string false "RFC 3339 time, inclusive"
+// @Param until query string false "RFC 3339 time, exclusive"
+// @Param before query int false "next_before of the previous page"
+// @Param limit query int false "Page size (1-500, default 50)"
+// @Success 200 {object} serializer.Response "Audit events"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 403 {object} serializer.Response "Access denied"
+// @Router /audit/events [get]
+func ListAuditEvents(c *gin.Context) {
+	var input AuditEventsInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+	listAuditEvents(c, input.filter())
+}
+
+// VerifyAuditEvents godoc
+// @Summary Verify the audit hash chain
+// @Description Recompute the hash of every audit event in order and report the first event that was modified or follows a deleted event
+// @Tags audit
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response{data=audit.VerifyResult} "Verification result"
+// @Failure 403 {object} serializer.Response "Access denied"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /audit/verify [get]
+func VerifyAuditEvents(c *gin.Context) {
+	result, err := audit.Verify(c)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.DBErr(i18n.T(c, "Audit.VerifyFailed"), err)))
+		return
+	}
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: result,
+	})
+}
+
+// UserSecurityEventsInput 查询当前用户的安全记录
+type UserSecurityEventsInput struct {
+	Action string `form:"action"`
+	Before uint   `form:"before"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// UserSecurityEvents godoc
+// @Summary Security history of the current user
+// @Description Logins (including failed attempts with this user name), logouts, device revocations and token refreshes of the current user, newest first
+// @Tags user
+// @Produce json
+// @Security ApiKeyAuth
+// @Param action query string false "Action, e.g. user.login"
+// @Param before query int false "next_before of the previous page"
+// @Param limit query int false "Page size (1-100, default 50)"
+// @Success 200 {object} serializer.Response "Security events"
+// @Failure 401 {object} serializer.Response "Unauthorized"
+// @Router /user/security-events [get]
+func UserSecurityEvents(c *gin.Context) {
+	var input UserSecurityEventsInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+	listAuditEvents(c, AuditEventsInput{
+		UserID: CurrentUser(c).ID,
+		Action: input.Action,
+		Before: input.Before,
+		Limit:  input.Limit,
+	}.filter())
+}
+
+func listAuditEvents(c *gin.Context, filter model.AuditFilter) {
+	events, err := model.ListAuditEvents(c, filter)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.DBErr(i18n.T(c, "Audit.ListFailed"), err)))
+		return
+	}
+	c.JSON(200, serializer.BuildAuditEvents(events, filter.Limit))
+}
+
+// recordAudit 记录当前请求的审计事件, err 不为 nil 时结果为 error
+func recordAudit(c *gin.Context, action, targetType, targetID string, err error) {
+	e := audit.FromRequest(c, action)
+	e.TargetType, e.TargetID = targetType, targetID
+	e.Outcome = audit.OutcomeSuccess
+	if err != nil {
+		e.Outcome, e.Reason = audit.OutcomeError, err.Error()
+	}
+	audit.Record(e)
+}
@@ -0,0 +1,366 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"openapphub/internal/audit"
+	"openapphub/internal/middleware"
+	"openapphub/internal/util"
+	"openapphub/pkg/cache"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClearCacheByPrefix godoc
+// @Summary Clear cache by prefix
+// @Description Clear all cached items with a specific prefix
+// @Tags cache
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param prefix body string true "Cache key prefix, must start with the key version, e.g. v1:/api/v1/items"
+// @Success 200 {object} serializer.Response "Cache cleared successfully"
+// @Failure 400 {object} serializer.Response "Bad request or prefix outside the response cache"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /cache/clear [post]
+func ClearCacheByPrefix(c *gin.Context) {
+	var input struct {
+		Prefix string `json:"prefix" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+
+	err := middleware.ClearCacheByPrefix(c, input.Prefix)
+	if errors.Is(err, middleware.ErrCacheKeyOutOfScope) {
+		c.JSON(400, serializer.ParamErr(i18n.T(c, "Cache.KeyOutOfScope"), err))
+		return
+	}
+	// 没有匹配的 key 也记为成功
+	auditErr := err
+	if errors.Is(err, cache.ErrNoKeysMatched) {
+		auditErr = nil
+	}
+	recordAudit(c, audit.ActionCacheClear, audit.TargetCache, input.Prefix, auditErr)
+	if err != nil {
+		if errors.Is(err, cache.ErrNoKeysMatched) {
+			c.JSON(200, serializer.Response{
+				Code: 0,
+				Msg:  i18n.T(c, "Cache.NoEntriesWithPrefix"),
+			})
+		} else {
+			c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.ClearFailed"), err)))
+		}
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Msg:  i18n.T(c, "Cache.Cleared"),
+	})
+}
+
+// RefreshCache godoc
+// @Summary Refresh cache for a specific key
+// @Description Refresh the cache for a specific key with a new duration
+// @Tags cache
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param refresh_info body RefreshCacheInput true "Refresh Cache Info"
+// @Success 200 {object} serializer.Response "Cache refreshed successfully"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /cache/refresh [post]
+func RefreshCache(c *gin.Context) {
+	var input RefreshCacheInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+
+	// 使用输入参数生成缓存键
+	var body []byte
+	if input.Body != "" {
+		body = []byte(input.Body)
+	}
+	key := middleware.GenerateCacheKeyFromParams(c, input.Method, input.Path, "", body)
+
+	util.LogCtx(c).Info("Attempting to refresh cache with key: " + middleware.RedactCacheKey(key))
+	exists, err := cache.Exists(c, key)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.CheckFailed"), err)))
+		return
+	}
+
+	if !exists {
+		c.JSON(200, serializer.Response{
+			Code: 0,
+			Msg:  i18n.T(c, "Cache.KeyNotFound"),
+		})
+		return
+	}
+
+	// 同时刷新命中计数的过期时间
+	err = middleware.RefreshCache(c, key, time.Duration(input.Duration)*time.Second)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.RefreshFailed"), err)))
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Msg:  i18n.T(c, "Cache.Refreshed"),
+	})
+}
+
+// InvalidateCache godoc
+// @Summary Invalidate cache for a specific key
+// @Description Remove a specific key from the cache
+// @Tags cache
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param invalidate_info body InvalidateCacheInput true "Invalidate Cache Info"
+// @Success 200 {object} serializer.Response "Cache invalidated successfully"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /cache/invalidate [post]
+func InvalidateCache(c *gin.Context) {
+	var input InvalidateCacheInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+
+	var body []byte
+	if input.Body != "" {
+		body = []byte(input.Body)
+	}
+
+	key := middleware.GenerateCacheKeyFromParams(c, input.Method, input.Path, "", body)
+	// key 中的查询参数可能带有令牌等敏感值, 日志和审计只记录隐藏后的 key
+	logged := middleware.RedactCacheKey(key)
+	util.LogCtx(c).Info(fmt.Sprintf("Attempting to invalidate cache with key: %s", logged))
+
+	// 尝试删除缓存
+	err := middleware.InvalidateCache(c, key)
+	auditErr := err
+	if errors.Is(err, cache.ErrNotFound) {
+		auditErr = nil
+	}
+	recordAudit(c, audit.ActionCacheInvalidate, audit.TargetCache, logged, auditErr)
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			util.LogCtx(c).Info(fmt.Sprintf("Cache key not found: %s", logged))
+			c.JSON(200, serializer.Response{
+				Code: 0,
+				Msg:  i18n.T(c, "Cache.KeyNotFound"),
+			})
+		} else {
+			util.LogCtx(c).Error(fmt.Sprintf("Failed to invalidate cache: %s, error: %s", logged, err.Error()))
+			c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.InvalidateFailed"), err)))
+		}
+		return
+	}
+
+	util.LogCtx(c).Info(fmt.Sprintf("Successfully invalidated cache key: %s", logged))
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Msg:  i18n.T(c, "Cache.Invalidated"),
+	})
+}
+
+type RefreshCacheInput struct {
+	Method   string `json:"method" binding:"required,oneof=GET POST"`
+	Path     string `json:"path" binding:"required"`
+	Body     string `json:"body"`
+	Duration int    `json:"duration" binding:"required,min=1"`
+}
+
+type InvalidateCacheInput struct {
+	Method string `json:"method" binding:"required,oneof=GET POST"`
+	Path   string `json:"path" binding:"required"`
+	Body   string `json:"body"`
+}
+
+// ListCacheEntries godoc
+// @Summary List cache entries
+// @Description List cached entries with a key prefix using cursor pagination
+// @Tags cache
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param prefix query string false "Cache key prefix starting with the key version, defaults to all entries of the current version"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Success 200 {object} serializer.Response "Cache entries"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /cache/entries [get]
+func ListCacheEntries(c *gin.Context) {
+	var input ListCacheEntriesInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+	if input.Limit == 0 {
+		input.Limit = 100
+	}
+
+	entries, next, err := middleware.ListCacheEntries(c, cache.Default(), input.Prefix, input.Cursor, input.Limit)
+	if errors.Is(err, middleware.ErrCacheKeyOutOfScope) {
+		c.JSON(400, serializer.ParamErr(i18n.T(c, "Cache.KeyOutOfScope"), err))
+		return
+	}
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.ListFailed"), err)))
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: gin.H{
+			"entries":     entries,
+			"next_cursor": next,
+		},
+	})
+}
+
+// InspectCacheEntry godoc
+// @Summary Inspect a cache entry
+// @Description Show route, size, TTL, age, tags and hit count of a cached entry
+// @Tags cache
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param key query string true "Cache key"
+// @Success 200 {object} serializer.Response "Cache entry details"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 404 {object} serializer.Response "Cache key not found"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /cache/entry [get]
+func InspectCacheEntry(c *gin.Context) {
+	var input InspectCacheEntryInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+
+	info, err := middleware.InspectCacheEntry(c, cache.Default(), input.Key)
+	if err != nil {
+		if errors.Is(err, middleware.ErrCacheKeyOutOfScope) {
+			c.JSON(400, serializer.ParamErr(i18n.T(c, "Cache.KeyOutOfScope"), err))
+		} else if errors.Is(err, cache.ErrNotFound) {
+			c.JSON(404, serializer.Response{
+				Code: 404,
+				Msg:  i18n.T(c, "Cache.KeyNotFound"),
+			})
+		} else {
+			c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.InspectFailed"), err)))
+		}
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: info,
+	})
+}
+
+// CacheStats godoc
+// @Summary Cache statistics
+// @Description Per-route hit, miss, successful store and admin invalidation counts collected by this instance; TTL expiry is not counted
+// @Tags cache
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Cache statistics"
+// @Router /cache/stats [get]
+func CacheStats(c *gin.Context) {
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: middleware.CacheStats(),
+	})
+}
+
+// WarmCache godoc
+// @Summary Pre-warm cache
+// @Description Replay a list of routes internally so their responses are cached
+// @Tags cache
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param warm_info body WarmCacheInput true "Routes to warm"
+// @Success 200 {object} serializer.Response "Warm results"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Router /cache/warm [post]
+func WarmCache(handler http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input WarmCacheInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(400, ErrorResponse(c, err))
+			return
+		}
+
+		results := middleware.WarmCache(c.Request.Context(), handler, input.Routes)
+		c.JSON(200, serializer.Response{
+			Code: 0,
+			Data: results,
+			Msg:  i18n.T(c, "Cache.Warmed"),
+		})
+	}
+}
+
+type ListCacheEntriesInput struct {
+	Prefix string `form:"prefix"`
+	Cursor string `form:"cursor"`
+	Limit  int64  `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+type InspectCacheEntryInput struct {
+	Key string `form:"key" binding:"required"`
+}
+
+type WarmCacheInput struct {
+	Routes []middleware.WarmRoute `json:"routes" binding:"required,min=1,max=100,dive"`
+}
+
+// CachePolicies godoc
+// @Summary Current cache policies
+// @Description Show the per-route cache policies currently in effect
+// @Tags cache
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Cache policies"
+// @Router /cache/policies [get]
+func CachePolicies(c *gin.Context) {
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: middleware.CurrentCachePolicies(),
+	})
+}
+
+// ReloadCachePolicies godoc
+// @Summary Reload cache policies
+// @Description Re-read the cache policy file; the previous policies stay in effect if the file is invalid
+// @Tags cache
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Cache policies reloaded"
+// @Failure 500 {object} serializer.Response "Invalid cache policy file"
+// @Router /cache/policies/reload [post]
+func ReloadCachePolicies(c *gin.Context) {
+	if err := middleware.ReloadCachePolicies(); err != nil {
+		c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "Cache.ReloadPoliciesFailed"), err)))
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: middleware.CurrentCachePolicies(),
+		Msg:  i18n.T(c, "Cache.PoliciesReloaded"),
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"openapphub/internal/config"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentConfig godoc
+// @Summary Effective configuration
+// @Description Configuration currently in effect on this instance, secrets are redacted
+// @Tags config
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Effective configuration"
+// @Router /config [get]
+func CurrentConfig(c *gin.Context) {
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: config.Current().Redacted(),
+	})
+}
+
+// ReloadConfig godoc
+// @Summary Reload configuration
+// @Description Re-read the config files and environment and apply reloadable settings; an invalid config is rejected and the current one kept
+// @Tags config
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Effective configuration after reload"
+// @Failure 400 {object} serializer.Response "Invalid configuration"
+// @Router /config/reload [post]
+func ReloadConfig(c *gin.Context) {
+	if err := config.Reload(); err != nil {
+		c.JSON(400, serializer.ParamErr(i18n.T(c, "Config.ReloadFailed"), err))
+		return
+	}
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: config.Current().Redacted(),
+	})
+}
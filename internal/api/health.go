@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"openapphub/internal/health"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz godoc
+// @Summary Liveness probe
+// @Description Reports that the process is serving requests; does not check dependencies, so a database outage does not restart the service
+// @Tags health
+// @Produce json
+// @Success 200 {object} serializer.Response "Alive"
+// @Router /healthz [get]
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, serializer.Response{
+		Code: 0,
+		Data: gin.H{"status": health.StatusOK},
+	})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Checks the database pool, Redis, the migration version and free disk space for logs; each result is cached for a few seconds. Only the overall status is returned, see /health/checks for the results of each check
+// @Tags health
+// @Produce json
+// @Success 200 {object} serializer.Response "Ready"
+// @Failure 503 {object} serializer.Response "Not ready"
+// @Router /readyz [get]
+func Readyz(c *gin.Context) {
+	report := health.Run(c)
+	// 探针不经过 IP 白名单, 只返回总体状态, 检查结果中的内部地址等信息只有管理接口能看到
+	writeReadiness(c, report.Status, gin.H{"status": report.Status})
+}
+
+// HealthChecks godoc
+// @Summary Readiness check results
+// @Description Result, duration and error of each readiness check and whether the instance is draining; returns 503 when not ready, like /readyz
+// @Tags health
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response{data=health.Report} "Ready"
+// @Failure 503 {object} serializer.Response{data=health.Report} "Not ready, see the failed checks"
+// @Router /health/checks [get]
+func HealthChecks(c *gin.Context) {
+	report := health.Run(c)
+	writeReadiness(c, report.Status, report)
+}
+
+func writeReadiness(c *gin.Context, status string, data interface{}) {
+	if status != health.StatusOK {
+		c.JSON(http.StatusServiceUnavailable, serializer.Response{
+			Code: http.StatusServiceUnavailable,
+			Data: data,
+			Msg:  i18n.T(c, "Health.NotReady"),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, serializer.Response{
+		Code: 0,
+		Data: data,
+	})
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/internal/health"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadyzHidesCheckResults(t *testing.T) {
+	health.Reset()
+	t.Cleanup(health.Reset)
+	_ = health.Register(health.Check{Name: "redis", Run: func(ctx context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"addr": "10.0.0.5:6379"}, errors.New("dial tcp 10.0.0.5:6379: connection refused")
+	}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/readyz", Readyz)
+	r.GET("/health/checks", HealthChecks)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"status":"fail"`) {
+		t.Fatalf("readyz = %d %s, want 503 with the status", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.5") || strings.Contains(w.Body.String(), "checks") {
+		t.Errorf("readyz exposes check results: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/checks", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "10.0.0.5") {
+		t.Errorf("health checks = %d %s, want the check results", w.Code, w.Body.String())
+	}
+}
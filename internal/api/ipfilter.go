@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"openapphub/internal/middleware"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListIPFilters godoc
+// @Summary List IP filters
+// @Description Show static and runtime allow/deny rules of every IP filter
+// @Tags ipfilter
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "IP filters"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /ipfilter [get]
+func ListIPFilters(c *gin.Context) {
+	filters, err := middleware.ListIPFilters(c)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "IPFilter.ListFailed"), err)))
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: filters,
+	})
+}
+
+// AddIPFilterEntry godoc
+// @Summary Add an IP filter rule
+// @Description Add an IP or CIDR to the runtime allow or deny list of a filter; all instances pick it up within 10 seconds
+// @Tags ipfilter
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param input body IPFilterEntryInput true "Filter, list and CIDR"
+// @Success 200 {object} serializer.Response "Rule added"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 404 {object} serializer.Response "Unknown filter"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /ipfilter/add [post]
+func AddIPFilterEntry(c *gin.Context) {
+	updateIPFilter(c, middleware.AddIPFilterEntry)
+}
+
+// RemoveIPFilterEntry godoc
+// @Summary Remove an IP filter rule
+// @Description Remove an IP or CIDR from the runtime allow or deny list of a filter
+// @Tags ipfilter
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param input body IPFilterEntryInput true "Filter, list and CIDR"
+// @Success 200 {object} serializer.Response "Rule removed"
+// @Failure 400 {object} serializer.Response "Bad request"
+// @Failure 404 {object} serializer.Response "Unknown filter"
+// @Failure 500 {object} serializer.Response "Internal server error"
+// @Router /ipfilter/remove [post]
+func RemoveIPFilterEntry(c *gin.Context) {
+	updateIPFilter(c, middleware.RemoveIPFilterEntry)
+}
+
+func updateIPFilter(c *gin.Context, update func(ctx context.Context, name, list, cidr string) error) {
+	var input IPFilterEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+
+	if err := update(c, input.Filter, input.List, input.CIDR); err != nil {
+		switch {
+		case errors.Is(err, middleware.ErrUnknownIPFilter):
+			c.JSON(404, serializer.Response{
+				Code: 404,
+				Msg:  i18n.T(c, "IPFilter.NotFound"),
+			})
+		case errors.Is(err, middleware.ErrInvalidIPFilterEntry):
+			c.JSON(400, serializer.ParamErr(i18n.T(c, "IPFilter.InvalidEntry"), err))
+		default:
+			c.JSON(500, serializer.Track(c, serializer.Err(500, i18n.T(c, "IPFilter.UpdateFailed"), err)))
+		}
+		return
+	}
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Msg:  i18n.T(c, "IPFilter.Updated"),
+	})
+}
+
+type IPFilterEntryInput struct {
+	Filter string `json:"filter" binding:"required"`
+	List   string `json:"list" binding:"required,oneof=allow deny"`
+	CIDR   string `json:"cidr" binding:"required"`
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"openapphub/internal/middleware"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LogLevelView 当前的日志级别
+type LogLevelView struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+// LogLevelInput 修改日志级别, 只修改提供的字段. packages 中级别为空的包取消覆盖
+type LogLevelInput struct {
+	Level    string            `json:"level" binding:"omitempty,oneof=debug info warn error"`
+	Packages map[string]string `json:"packages"`
+}
+
+// GetLogLevel godoc
+// @Summary Log level
+// @Description Global log level and per-package overrides currently in effect on this instance
+// @Tags log
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response{data=LogLevelView} "Log level"
+// @Failure 403 {object} serializer.Response "Access denied"
+// @Router /log/level [get]
+func GetLogLevel(c *gin.Context) {
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: currentLogLevel(),
+	})
+}
+
+// SetLogLevel godoc
+// @Summary Change log level
+// @Description Change the global log level and per-package overrides of this instance at runtime; the next config reload restores the configured levels
+// @Tags log
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param input body LogLevelInput true "Level and package overrides"
+// @Success 200 {object} serializer.Response{data=LogLevelView} "Log level updated"
+// @Failure 400 {object} serializer.Response "Invalid level"
+// @Failure 403 {object} serializer.Response "Access denied"
+// @Router /log/level [put]
+func SetLogLevel(c *gin.Context) {
+	var input LogLevelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(400, ErrorResponse(c, err))
+		return
+	}
+
+	// 先合并校验包的级别, 不合法时不做任何修改
+	packages := middleware.PackageLogLevels()
+	for name, level := range input.Packages {
+		if level == "" {
+			delete(packages, name)
+		} else {
+			packages[name] = level
+		}
+	}
+	if err := middleware.SetPackageLogLevels(packages); err != nil {
+		c.JSON(400, serializer.ParamErr(i18n.T(c, "Log.InvalidLevel"), err))
+		return
+	}
+	if input.Level != "" {
+		if err := middleware.SetLogLevel(input.Level); err != nil {
+			c.JSON(400, serializer.ParamErr(i18n.T(c, "Log.InvalidLevel"), err))
+			return
+		}
+	}
+	middleware.RequestLogger(c).Info("Log level changed",
+		zap.String("level", middleware.LogLevel()),
+		zap.Any("packages", packages))
+
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: currentLogLevel(),
+		Msg:  i18n.T(c, "Log.Updated"),
+	})
+}
+
+func currentLogLevel() LogLevelView {
+	return LogLevelView{
+		Level:    middleware.LogLevel(),
+		Packages: middleware.PackageLogLevels(),
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"openapphub/internal/middleware"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStats godoc
+// @Summary Rate limiter health
+// @Description Circuit breaker state and degraded request counts per rate limit policy on this instance
+// @Tags ratelimit
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Rate limiter statistics"
+// @Router /ratelimit/stats [get]
+func RateLimitStats(c *gin.Context) {
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: middleware.RateLimitStatsSnapshot(),
+	})
+}
+
+// ConcurrencyStats godoc
+// @Summary Concurrency limiter state
+// @Description In-flight and queued requests, shed and timed out counts per concurrency policy on this instance
+// @Tags ratelimit
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} serializer.Response "Concurrency statistics"
+// @Router /concurrency/stats [get]
+func ConcurrencyStats(c *gin.Context) {
+	c.JSON(200, serializer.Response{
+		Code: 0,
+		Data: middleware.ConcurrencyStatsSnapshot(),
+	})
+}
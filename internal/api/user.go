@@ -1,11 +1,14 @@
 package api
 
 import (
+	"openapphub/internal/audit"
 	"openapphub/internal/auth"
+	"openapphub/internal/metrics"
 	"openapphub/internal/model"
 	"openapphub/internal/service"
+	"openapphub/pkg/i18n"
 	"openapphub/pkg/serializer"
-	"os"
+	"strconv"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -24,10 +27,10 @@ import (
 func UserRegister(c *gin.Context) {
 	var service service.UserRegisterService
 	if err := c.ShouldBind(&service); err == nil {
-		res := service.Register()
-		c.JSON(200, res)
+		res := service.Register(c)
+		c.JSON(200, trackServerError(c, res))
 	} else {
-		c.JSON(200, ErrorResponse(err))
+		c.JSON(200, ErrorResponse(c, err))
 	}
 }
 
@@ -46,9 +49,23 @@ func UserLogin(c *gin.Context) {
 	var service service.UserLoginService
 	if err := c.ShouldBind(&service); err == nil {
 		res := service.Login(c)
-		c.JSON(200, res)
+		metrics.LoginAttempts.WithLabelValues(loginResult(res)).Inc()
+		c.JSON(200, trackServerError(c, res))
 	} else {
-		c.JSON(200, ErrorResponse(err))
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginFailure).Inc()
+		c.JSON(200, ErrorResponse(c, err))
+	}
+}
+
+// loginResult 按响应码区分登录成功、失败和服务器错误
+func loginResult(res serializer.Response) string {
+	switch {
+	case res.Code == 0:
+		return metrics.LoginSuccess
+	case res.Code >= serializer.CodeInternalServerError:
+		return metrics.LoginError
+	default:
+		return metrics.LoginFailure
 	}
 }
 
@@ -68,6 +85,27 @@ func UserMe(c *gin.Context) {
 	c.JSON(200, res)
 }
 
+// UserSetLocale godoc
+// @Summary Set preferred locale
+// @Description Set the locale used for messages returned to the current user; an empty locale falls back to Accept-Language
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param input body service.UserLocaleService true "Locale, e.g. en-US"
+// @Success 200 {object} serializer.Response "User information with the new locale"
+// @Failure 401 {object} serializer.Response "Unauthorized"
+// @Router /user/locale [put]
+func UserSetLocale(c *gin.Context) {
+	var service service.UserLocaleService
+	if err := c.ShouldBind(&service); err == nil {
+		res := service.Update(c, CurrentUser(c))
+		c.JSON(200, trackServerError(c, res))
+	} else {
+		c.JSON(200, ErrorResponse(c, err))
+	}
+}
+
 // UserLogout godoc
 // @Summary Log out a user
 // @Description Log out the currently authenticated user
@@ -79,40 +117,43 @@ func UserMe(c *gin.Context) {
 // @Failure 401 {object} serializer.Response "Unauthorized"
 // @Router /user/logout [delete]
 func UserLogout(c *gin.Context) {
-	authMode := os.Getenv("AUTH_MODE")
+	authMode := auth.Mode()
 	user := CurrentUser(c)
 
 	if user == nil {
 		c.JSON(401, serializer.Response{
 			Code: 401,
-			Msg:  "未登录",
+			Msg:  i18n.T(c, "User.NotLoggedIn"),
 		})
 		return
 	}
 
-	if authMode == "jwt" {
+	if authMode == auth.ModeJWT {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
 			c.JSON(400, serializer.Response{
 				Code: 400,
-				Msg:  "未提供令牌",
+				Msg:  i18n.T(c, "User.TokenMissing"),
 			})
 			return
 		}
-		err := model.DeleteJWTToken(tokenString)
+		err := model.DeleteJWTToken(c, tokenString)
+		recordAudit(c, audit.ActionLogout, audit.TargetToken, audit.Fingerprint(tokenString), err)
 		if err != nil {
-			c.JSON(500, serializer.DBErr("注销失败", err))
+			c.JSON(500, serializer.Track(c, serializer.DBErr(i18n.T(c, "User.LogoutFailed"), err)))
 			return
 		}
 	} else {
 		s := sessions.Default(c)
+		sessionID, _ := s.Get("session_id").(string)
 		s.Clear()
-		s.Save()
+		err := s.Save()
+		recordAudit(c, audit.ActionLogout, audit.TargetSession, audit.Fingerprint(sessionID), err)
 	}
 
 	c.JSON(200, serializer.Response{
 		Code: 0,
-		Msg:  "登出成功",
+		Msg:  i18n.T(c, "User.LoggedOut"),
 	})
 }
 
@@ -120,76 +161,74 @@ func UserLogout(c *gin.Context) {
 
 func UserLogoutAll(c *gin.Context) {
 	user := CurrentUser(c)
-	authMode := os.Getenv("AUTH_MODE")
+	authMode := auth.Mode()
 
-	if authMode == "jwt" {
-		err := model.DeleteAllJWTTokensForUser(user.ID)
-		if err != nil {
-			c.JSON(500, serializer.DBErr("注销所有设备失败", err))
-			return
-		}
+	var err error
+	if authMode == auth.ModeJWT {
+		err = model.DeleteAllJWTTokensForUser(c, user.ID)
 	} else {
-		err := model.DeleteAllSessionsForUser(user.ID)
-		if err != nil {
-			c.JSON(500, serializer.DBErr("注销所有设备失败", err))
-			return
-		}
+		err = model.DeleteAllSessionsForUser(c, user.ID)
+	}
+	recordAudit(c, audit.ActionLogoutAll, audit.TargetUser, strconv.FormatUint(uint64(user.ID), 10), err)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.DBErr(i18n.T(c, "User.LogoutAllFailed"), err)))
+		return
 	}
 
 	c.JSON(200, serializer.Response{
 		Code: 0,
-		Msg:  "已注销所有设备",
+		Msg:  i18n.T(c, "User.LoggedOutAll"),
 	})
 }
 
 func UserLogoutDevice(c *gin.Context) {
 	user := CurrentUser(c)
 	deviceID := c.Param("device_id")
-	authMode := os.Getenv("AUTH_MODE")
+	authMode := auth.Mode()
 
 	if user == nil {
 		c.JSON(401, serializer.Response{
 			Code: 401,
-			Msg:  "未登录",
+			Msg:  i18n.T(c, "User.NotLoggedIn"),
 		})
 		return
 	}
 
-	if authMode == "jwt" {
-		err := model.DeleteJWTToken(deviceID)
-		if err != nil {
-			c.JSON(500, serializer.DBErr("注销设备失败", err))
-			return
-		}
+	var err error
+	target := audit.TargetSession
+	if authMode == auth.ModeJWT {
+		target = audit.TargetToken
+		err = model.DeleteJWTToken(c, deviceID)
 	} else {
-		err := model.DeleteSession(deviceID)
-		if err != nil {
-			c.JSON(500, serializer.DBErr("注销设备失败", err))
-			return
-		}
+		err = model.DeleteSession(c, deviceID)
+	}
+	recordAudit(c, audit.ActionDeviceRevoke, target, audit.Fingerprint(deviceID), err)
+	if err != nil {
+		c.JSON(500, serializer.Track(c, serializer.DBErr(i18n.T(c, "User.LogoutDeviceFailed"), err)))
+		return
 	}
 
 	c.JSON(200, serializer.Response{
 		Code: 0,
-		Msg:  "已注销指定设备",
+		Msg:  i18n.T(c, "User.LoggedOutDevice"),
 	})
 }
 
 func UserDevices(c *gin.Context) {
 	user := CurrentUser(c)
-	authMode := os.Getenv("AUTH_MODE")
+	authMode := auth.Mode()
 
 	var devices interface{}
 	var err error
 
-	if authMode == "jwt" {
-		devices, err = model.GetActiveJWTTokensForUser(user.ID)
+	if authMode == auth.ModeJWT {
+		devices, err = model.GetActiveJWTTokensForUser(c, user.ID)
 	} else {
-		devices, err = model.GetActiveSessionsForUser(user.ID)
+		devices, err = model.GetActiveSessionsForUser(c, user.ID)
 	}
 
 	if err != nil {
-		c.JSON(500, serializer.DBErr("获取设备列表失败", err))
+		c.JSON(500, serializer.Track(c, serializer.DBErr(i18n.T(c, "User.ListDevicesFailed"), err)))
 		return
 	}
 
@@ -218,22 +257,38 @@ func RefreshToken(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(400, ErrorResponse(err))
+		c.JSON(400, ErrorResponse(c, err))
 		return
 	}
 
-	newAccessToken, err := auth.RefreshToken(input.RefreshToken)
+	// 解析出用户后再生成访问令牌, 审计记录中包含令牌所属的用户
+	e := audit.FromRequest(c, audit.ActionTokenRefresh)
+	e.TargetType, e.TargetID = audit.TargetToken, audit.Fingerprint(input.RefreshToken)
+	claims, err := auth.ParseRefreshToken(input.RefreshToken)
 	if err != nil {
+		e.Outcome, e.Reason = audit.OutcomeFailure, "invalid_refresh_token"
+		audit.Record(e)
 		c.JSON(401, serializer.Response{
 			Code: 401,
-			Msg:  "Invalid refresh token",
+			Msg:  i18n.T(c, "User.InvalidRefreshToken"),
 		})
 		return
 	}
+	e.ActorID = claims.UserID
+
+	newAccessToken, err := auth.GenerateToken(claims.UserID)
+	if err != nil {
+		e.Outcome, e.Reason = audit.OutcomeError, err.Error()
+		audit.Record(e)
+		c.JSON(500, serializer.Track(c, serializer.Err(serializer.CodeEncryptError, i18n.T(c, "User.GenerateTokenFailed"), err)))
+		return
+	}
+	e.Outcome = audit.OutcomeSuccess
+	audit.Record(e)
 
 	c.JSON(200, serializer.Response{
 		Code: 0,
 		Data: gin.H{"access_token": newAccessToken},
-		Msg:  "Token refreshed successfully",
+		Msg:  i18n.T(c, "User.TokenRefreshed"),
 	})
 }
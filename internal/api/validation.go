@@ -0,0 +1,113 @@
+package api
+
+import (
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	validator "github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+var (
+	translators *ut.UniversalTranslator
+	setupOnce   sync.Once
+	setupErr    error
+)
+
+// SetupValidator 配置 gin 的参数校验: 错误中的字段名使用 json 标签, 并注册各语言的错误消息.
+// 可以重复调用, 只有第一次生效
+func SetupValidator() error {
+	setupOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(fieldName)
+
+		translators = ut.New(zh.New(), zh.New(), en.New())
+		for language, register := range map[string]func(*validator.Validate, ut.Translator) error{
+			"zh": zh_translations.RegisterDefaultTranslations,
+			"en": en_translations.RegisterDefaultTranslations,
+		} {
+			trans, _ := translators.GetTranslator(language)
+			if setupErr = register(v, trans); setupErr != nil {
+				return
+			}
+		}
+	})
+	return setupErr
+}
+
+// fieldName 返回字段在请求中的名称: json 标签, 其次 form 标签, 都没有时使用字段名
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validationErrors 把校验错误转换为按请求语言翻译的字段错误列表.
+// 消息优先使用翻译目录中的 Tag.<tag>, 其次使用 go-playground 的翻译, 都没有时使用 Tag.default;
+// 消息中的字段名替换为翻译目录中的 Field.<field>
+func validationErrors(c *gin.Context, ve validator.ValidationErrors) []serializer.FieldError {
+	var trans ut.Translator
+	if translators != nil {
+		language, _, _ := strings.Cut(i18n.Locale(c), "-")
+		trans, _ = translators.GetTranslator(language)
+	}
+
+	errs := make([]serializer.FieldError, 0, len(ve))
+	for _, fe := range ve {
+		label := fieldLabel(c, fe.Field())
+		params := i18n.Params{"field": label, "param": fe.Param()}
+
+		message := i18n.T(c, "Tag."+fe.Tag(), params)
+		if message == "Tag."+fe.Tag() {
+			message = i18n.T(c, "Tag.default", params)
+			if trans != nil {
+				if translated := fe.Translate(trans); translated != fe.Error() {
+					message = strings.Replace(translated, fe.Field(), label, 1)
+				}
+			}
+		}
+
+		errs = append(errs, serializer.FieldError{
+			Field:   fieldPath(fe.Namespace()),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: message,
+		})
+	}
+	return errs
+}
+
+// fieldLabel 返回字段的显示名称, 翻译目录中没有时使用字段名
+func fieldLabel(c *gin.Context, field string) string {
+	if label := i18n.T(c, "Field."+field); label != "Field."+field {
+		return label
+	}
+	return field
+}
+
+// fieldPath 去掉命名空间中的结构体名称, 例如 UserRegisterService.user_name -> user_name
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
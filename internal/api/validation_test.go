@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http/httptest"
+	"openapphub/pkg/i18n"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	validator "github.com/go-playground/validator/v10"
+)
+
+type validationInput struct {
+	UserName string `json:"user_name" binding:"required,min=5"`
+	Password string `json:"password" binding:"required"`
+	Items    []struct {
+		Name string `json:"name" binding:"required"`
+	} `json:"items" binding:"dive"`
+}
+
+func TestErrorResponseListsAllFields(t *testing.T) {
+	if err := i18n.LoadDir("../config/locales"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetupValidator(); err != nil {
+		t.Fatal(err)
+	}
+
+	var input validationInput
+	input.UserName = "abc"
+	input.Items = make([]struct {
+		Name string `json:"name" binding:"required"`
+	}, 1)
+	ve, ok := binding.Validator.ValidateStruct(input).(validator.ValidationErrors)
+	if !ok {
+		t.Fatal("expected validation errors")
+	}
+
+	tests := []struct {
+		locale string
+		want   map[string]string
+	}{
+		{"zh-CN", map[string]string{
+			"user_name":     "用户名长度必须至少为5个字符",
+			"password":      "密码为必填字段",
+			"items[0].name": "名称为必填字段",
+		}},
+		{"en-US", map[string]string{
+			"user_name":     "User name must be at least 5 characters in length",
+			"password":      "Password is a required field",
+			"items[0].name": "Name is a required field",
+		}},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(i18n.ContextKey, tt.locale)
+		res := ErrorResponse(c, ve)
+		errs := validationErrors(c, ve)
+		if len(errs) != len(tt.want) {
+			t.Fatalf("%s: got %d errors, want %d", tt.locale, len(errs), len(tt.want))
+		}
+		for _, fe := range errs {
+			if fe.Message != tt.want[fe.Field] {
+				t.Errorf("%s: %s = %q, want %q", tt.locale, fe.Field, fe.Message, tt.want[fe.Field])
+			}
+		}
+		if res.Msg != errs[0].Message {
+			t.Errorf("%s: msg = %q, want first field error %q", tt.locale, res.Msg, errs[0].Message)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package audit 记录登录、注销、设备下线、令牌刷新和缓存清理等安全相关事件.
+// 事件异步写入 audit_events 表, 可以同时按行写入 JSON 文件. 每条记录带有上一条记录的哈希,
+// 组成哈希链, 修改或删除中间的记录后 Verify 会报告第一条不一致的记录
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"openapphub/internal/metrics"
+	"openapphub/internal/model"
+	"openapphub/internal/util"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 事件类型
+const (
+	ActionLogin           = "user.login"
+	ActionLogout          = "user.logout"
+	ActionLogoutAll       = "user.logout_all"
+	ActionDeviceRevoke    = "user.device_revoke"
+	ActionTokenRefresh    = "user.token_refresh"
+	ActionCacheClear      = "cache.clear"
+	ActionCacheInvalidate = "cache.invalidate"
+)
+
+// 事件结果
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure" // 被拒绝, 例如密码错误
+	OutcomeError   = "error"   // 服务器错误
+)
+
+// 事件目标类型
+const (
+	TargetUser    = model.AuditTargetUser
+	TargetToken   = "token"   // TargetID 为令牌的指纹, 不记录令牌本身
+	TargetSession = "session" // TargetID 为会话 ID 的指纹
+	TargetCache   = "cache"   // TargetID 为缓存 key 或前缀
+)
+
+// Event 一条审计事件
+type Event struct {
+	Time       time.Time // 为零时使用写入队列的时间
+	Action     string
+	ActorID    uint   // 执行操作的用户, 匿名请求为 0
+	Actor      string // 用户名, 登录失败时为提交的用户名
+	TargetType string
+	TargetID   string
+	IP         string
+	UserAgent  string
+	Outcome    string
+	Reason     string // 失败原因, 例如 invalid_password
+	RequestID  string
+	Details    map[string]string
+}
+
+// Options 审计日志配置
+type Options struct {
+	Enabled bool
+	// File 同时按行写入 JSON 的文件, 为空时只写数据库
+	File string
+	// QueueSize 等待写入的事件数量上限, 队列满时丢弃新事件并记录错误日志
+	QueueSize int
+}
+
+// 写入结果, 见 metrics.AuditEvents
+const (
+	statusWritten = "written"
+	statusFailed  = "failed"
+	statusDropped = "dropped"
+)
+
+var (
+	mu      sync.RWMutex
+	current *writer
+)
+
+// writer 在单独的 goroutine 中按顺序写入事件, 保证同一实例的哈希链不会交错
+type writer struct {
+	events chan Event
+	file   *os.File
+	done   chan struct{}
+}
+
+// Init 按配置启动写入审计事件的 goroutine, 需要在连接数据库之后调用
+func Init(opts Options) error {
+	if !opts.Enabled {
+		return nil
+	}
+	if opts.QueueSize <= 0 {
+		return fmt.Errorf("audit queue size must be positive, got %d", opts.QueueSize)
+	}
+	w := &writer{
+		events: make(chan Event, opts.QueueSize),
+		done:   make(chan struct{}),
+	}
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0o755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		w.file = file
+	}
+	go w.run()
+
+	mu.Lock()
+	previous := current
+	current = w
+	mu.Unlock()
+	if previous != nil {
+		previous.close(context.Background())
+	}
+	return nil
+}
+
+// Record 把事件加入写入队列, 不等待写入完成; 没有启用审计日志时忽略
+func Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if current == nil {
+		return
+	}
+	select {
+	case current.events <- e:
+	default:
+		metrics.AuditEvents.WithLabelValues(statusDropped).Inc()
+		util.Log().Error("审计日志队列已满, 丢弃事件: %s %s %s", e.Action, e.Actor, e.Outcome)
+	}
+}
+
+// Close 停止接收新事件, 等待队列中的事件写入完成或 ctx 结束
+func Close(ctx context.Context) error {
+	mu.Lock()
+	w := current
+	current = nil
+	mu.Unlock()
+	if w == nil {
+		return nil
+	}
+	return w.close(ctx)
+}
+
+func (w *writer) close(ctx context.Context) error {
+	close(w.events)
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return fmt.Errorf("audit: %d events not written: %w", len(w.events), ctx.Err())
+	}
+	if w.file != nil {
+		return w.file.Close()
+	}
+	return nil
+}
+
+func (w *writer) run() {
+	defer close(w.done)
+	for e := range w.events {
+		w.write(e)
+	}
+}
+
+// write 写入数据库和文件. 数据库写入失败时仍写入文件, 但这条记录没有哈希, 不在哈希链中
+func (w *writer) write(e Event) {
+	row, err := newRow(e)
+	if err == nil {
+		err = insert(row)
+	}
+	if err != nil {
+		row.PrevHash, row.Hash = "", ""
+		metrics.AuditEvents.WithLabelValues(statusFailed).Inc()
+		util.Log().Error("审计日志写入失败: %s %s %s: %v", e.Action, e.Actor, e.Outcome, err)
+	} else {
+		metrics.AuditEvents.WithLabelValues(statusWritten).Inc()
+	}
+
+	if w.file != nil {
+		line, err := json.Marshal(row)
+		if err == nil {
+			_, err = w.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			util.Log().Error("审计日志文件写入失败: %v", err)
+		}
+	}
+}
+
+// insertRetries 多个实例同时写入时, 接在同一条记录后面的写入会失败并重试
+const insertRetries = 5
+
+// insert 接在最后一条记录后面写入. prev_hash 是唯一索引, 多个实例同时接在同一条记录后面时
+// 只有一个能写入, 其余的重新读取最后一条记录后重试, 哈希链不会分叉
+func insert(row *model.AuditEvent) error {
+	if model.DB == nil {
+		return errors.New("database not connected")
+	}
+	var err error
+	for i := 0; i < insertRetries; i++ {
+		var last model.AuditEvent
+		if err = model.DB.Select("id", "hash").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		row.ID = 0
+		row.PrevHash = last.Hash
+		row.Hash = Hash(row)
+		if err = model.DB.Create(row).Error; !isDuplicate(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// newRow 把事件转换为数据库记录, 时间精确到微秒并转为 UTC, 与数据库中保存的值一致
+func newRow(e Event) (*model.AuditEvent, error) {
+	row := &model.AuditEvent{
+		OccurredAt: e.Time.UTC().Truncate(time.Microsecond),
+		Action:     e.Action,
+		ActorID:    e.ActorID,
+		Actor:      truncate(e.Actor, 255),
+		TargetType: e.TargetType,
+		TargetID:   truncate(e.TargetID, 255),
+		IP:         e.IP,
+		UserAgent:  truncate(e.UserAgent, 512),
+		Outcome:    e.Outcome,
+		Reason:     truncate(e.Reason, 255),
+		RequestID:  e.RequestID,
+	}
+	if len(e.Details) > 0 {
+		details, err := json.Marshal(e.Details)
+		if err != nil {
+			return row, err
+		}
+		row.Details = string(details)
+	}
+	return row, nil
+}
+
+// Hash 计算记录的哈希: SHA-256(上一条记录的哈希 + 本条记录除 ID 和哈希外的内容)
+func Hash(row *model.AuditEvent) string {
+	content, _ := json.Marshal([]interface{}{
+		row.OccurredAt.UTC().Format(time.RFC3339Nano),
+		row.Action,
+		row.ActorID,
+		row.Actor,
+		row.TargetType,
+		row.TargetID,
+		row.IP,
+		row.UserAgent,
+		row.Outcome,
+		row.Reason,
+		row.RequestID,
+		row.Details,
+	})
+	sum := sha256.Sum256(append([]byte(row.PrevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint 返回令牌、会话 ID 等敏感值的指纹, 可以用来关联同一个值的记录, 但不能还原
+func Fingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
+
+// truncate 按字符截断, 避免超过列的长度
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"openapphub/internal/model"
+	"openapphub/internal/util"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHashChain(t *testing.T) {
+	first, err := newRow(Event{Time: time.Now(), Action: ActionLogin, Actor: "alice", Outcome: OutcomeSuccess, Details: map[string]string{"device_info": "phone"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Hash = Hash(first)
+	second, _ := newRow(Event{Time: time.Now(), Action: ActionLogout, ActorID: 1, Outcome: OutcomeSuccess})
+	second.PrevHash = first.Hash
+	second.Hash = Hash(second)
+
+	// 从数据库读出的时间在本地时区, 哈希不变
+	reloaded := *first
+	reloaded.OccurredAt = reloaded.OccurredAt.In(time.FixedZone("CST", 8*3600))
+	if Hash(&reloaded) != first.Hash {
+		t.Error("hash depends on the time zone")
+	}
+
+	tampered := *first
+	tampered.Outcome = OutcomeFailure
+	if Hash(&tampered) == first.Hash {
+		t.Error("modified event has the same hash")
+	}
+
+	// 删除第一条后, 第二条的 PrevHash 对不上
+	relinked := *second
+	relinked.PrevHash = ""
+	if Hash(&relinked) == second.Hash {
+		t.Error("hash does not depend on the previous hash")
+	}
+}
+
+func TestRecordWritesFile(t *testing.T) {
+	util.BuildLogger(zap.NewNop())
+	model.DB = nil
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := Init(Options{Enabled: true, File: file, QueueSize: 8}); err != nil {
+		t.Fatal(err)
+	}
+	Record(Event{Action: ActionCacheClear, TargetType: TargetCache, TargetID: "api:", Outcome: OutcomeSuccess})
+	Record(Event{Action: ActionTokenRefresh, TargetType: TargetToken, TargetID: Fingerprint("secret"), Outcome: OutcomeFailure})
+	if err := Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后的事件被忽略
+	Record(Event{Action: ActionLogin})
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var actions []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var row model.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatal(err)
+		}
+		actions = append(actions, row.Action)
+	}
+	if len(actions) != 2 || actions[0] != ActionCacheClear || actions[1] != ActionTokenRefresh {
+		t.Errorf("file has %v", actions)
+	}
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"openapphub/internal/middleware"
+	"openapphub/internal/model"
+	"openapphub/internal/util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FromRequest 返回带有客户端地址、User-Agent、请求 ID 和当前登录用户的事件, 调用方再填写目标和结果
+func FromRequest(c *gin.Context, action string) Event {
+	e := Event{
+		Action:    action,
+		IP:        middleware.ClientIP(c),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: util.RequestID(c),
+	}
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok && u != nil {
+			e.ActorID = u.ID
+			e.Actor = u.UserName
+		}
+	}
+	return e
+}
+
+// SetUserTarget 以用户为目标, 用户可以在自己的安全记录中看到, 例如别人用自己的用户名登录失败
+func (e *Event) SetUserTarget(userID uint) {
+	e.TargetType = TargetUser
+	e.TargetID = strconv.FormatUint(uint64(userID), 10)
+}
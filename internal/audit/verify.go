@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"openapphub/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize 校验时每次读取的记录数
+const verifyBatchSize = 1000
+
+// errBroken 找到不一致的记录后停止读取
+var errBroken = errors.New("audit chain broken")
+
+// VerifyResult 哈希链的校验结果
+type VerifyResult struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"` // 校验过的记录数, 链断开时不包括断开的记录
+	// BrokenID 第一条不一致的记录: 内容被修改, 或者与前一条记录之间的记录被删除
+	BrokenID uint   `json:"broken_id,omitempty"`
+	LastHash string `json:"last_hash,omitempty"`
+}
+
+// Verify 按 ID 顺序重新计算所有记录的哈希, 返回第一条不一致的记录. 数据库写入失败、只写入了文件的事件不在链中
+func Verify(ctx context.Context) (*VerifyResult, error) {
+	if model.DB == nil {
+		return nil, errors.New("database not connected")
+	}
+	result := &VerifyResult{Valid: true}
+	var rows []model.AuditEvent
+	err := model.DB.WithContext(ctx).FindInBatches(&rows, verifyBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range rows {
+			row := &rows[i]
+			if row.PrevHash != result.LastHash || row.Hash != Hash(row) {
+				result.Valid = false
+				result.BrokenID = row.ID
+				return errBroken
+			}
+			result.Checked++
+			result.LastHash = row.Hash
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errBroken) {
+		return nil, err
+	}
+	return result, nil
+}
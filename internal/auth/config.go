@@ -0,0 +1,29 @@
+package auth
+
+import "time"
+
+// 认证方式
+const (
+	ModeSession = "session"
+	ModeJWT     = "jwt"
+)
+
+// Config 认证配置
+type Config struct {
+	Mode              string // session 或 jwt
+	Secret            string // JWT 签名密钥
+	Expiration        time.Duration
+	RefreshExpiration time.Duration
+}
+
+var conf = Config{Mode: ModeSession}
+
+// Configure 设置认证配置, 需要在处理请求之前调用
+func Configure(c Config) {
+	conf = c
+}
+
+// Mode 返回当前的认证方式
+func Mode() string {
+	return conf.Mode
+}
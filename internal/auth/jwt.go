@@ -1,11 +1,12 @@
 package auth
 
 import (
+	"context"
 	"errors"
-	"os"
 	"time"
 
 	"openapphub/internal/model"
+	"openapphub/internal/tracing"
 
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -22,31 +23,29 @@ type RefreshClaims struct {
 
 func GenerateTokenPair(user model.User) (string, string, error) {
 	// Access token
-	expirationTime, _ := time.ParseDuration(os.Getenv("JWT_EXPIRATION"))
 	claims := &Claims{
 		UserID: user.ID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expirationTime)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(conf.Expiration)),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	accessToken, err := token.SignedString([]byte(conf.Secret))
 	if err != nil {
 		return "", "", err
 	}
 
 	// Refresh token
-	refreshExpirationTime, _ := time.ParseDuration(os.Getenv("JWT_REFRESH_EXPIRATION"))
 	refreshClaims := &RefreshClaims{
 		UserID: user.ID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshExpirationTime)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(conf.RefreshExpiration)),
 		},
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	refreshTokenString, err := refreshToken.SignedString([]byte(conf.Secret))
 	if err != nil {
 		return "", "", err
 	}
@@ -54,9 +53,13 @@ func GenerateTokenPair(user model.User) (string, string, error) {
 	return accessToken, refreshTokenString, nil
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
+// ParseToken 校验并解析访问令牌, 在 ctx 的 span 下记录一个 auth.parse_token span
+func ParseToken(ctx context.Context, tokenString string) (claims *Claims, err error) {
+	_, span := tracing.Start(ctx, "auth.parse_token")
+	defer func() { tracing.End(span, err) }()
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
+		return []byte(conf.Secret), nil
 	})
 
 	if err != nil {
@@ -87,7 +90,7 @@ func RefreshToken(refreshToken string) (string, error) {
 
 func ParseRefreshToken(tokenString string) (*RefreshClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &RefreshClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
+		return []byte(conf.Secret), nil
 	})
 
 	if err != nil {
@@ -102,14 +105,13 @@ func ParseRefreshToken(tokenString string) (*RefreshClaims, error) {
 }
 
 func GenerateToken(userID uint) (string, error) {
-	expirationTime, _ := time.ParseDuration(os.Getenv("JWT_EXPIRATION"))
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expirationTime)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(conf.Expiration)),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return token.SignedString([]byte(conf.Secret))
 }
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"openapphub/internal/model"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestParseTokenSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		_ = tp.Shutdown(context.Background())
+	})
+	defer Configure(conf)
+	Configure(Config{Mode: ModeJWT, Secret: "test-secret", Expiration: time.Minute, RefreshExpiration: time.Hour})
+
+	accessToken, _, err := GenerateTokenPair(model.User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	if _, err := ParseToken(ctx, accessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(ctx, accessToken+"x"); err == nil {
+		t.Fatal("expected a tampered token to be rejected")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want one per parse and the parent", len(spans))
+	}
+	for i, want := range []codes.Code{codes.Unset, codes.Error} {
+		span := spans[i]
+		if span.Name() != "auth.parse_token" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d = %q, want an auth.parse_token child of the request span", i, span.Name())
+		}
+		if span.Status().Code != want {
+			t.Errorf("span %d status = %v, want %v", i, span.Status().Code, want)
+		}
+	}
+}
@@ -1,53 +1,176 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"openapphub/internal/audit"
+	"openapphub/internal/auth"
+	"openapphub/internal/health"
+	"openapphub/internal/metrics"
 	"openapphub/internal/middleware"
 	"openapphub/internal/model"
+	"openapphub/internal/tracing"
 	"openapphub/internal/util"
 	"openapphub/pkg/cache"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/redact"
 	"os"
 	"path/filepath"
-
-	"github.com/joho/godotenv"
+	"time"
 )
 
-// Init 初始化配置项
-func Init() {
-	// 从本地读取环境变量
-	godotenv.Load()
-	// 输出一下环境变量
-	fmt.Println(os.Getenv("GIN_MODE"))
-	fmt.Println(os.Getenv("PORT"))
-	fmt.Println(os.Getenv("MYSQL_DSN"))
-	fmt.Println(os.Getenv("REDIS_DSN"))
-
-	// 初始化 zap logger
-	middleware.InitLogger()
+// Init 按配置初始化各个模块, conf 需要先通过 Load 加载和校验
+func Init(conf *Config) {
+	// 按日志配置初始化 zap logger, 日志级别之后可以通过热更新或管理接口修改
+	redact.Configure(conf.RedactRules())
+	if err := middleware.InitLogger(conf.LogOptions()); err != nil {
+		panic(err)
+	}
 
 	// 使用 middleware 中的 zapLogger 初始化 util.Logger
 	util.BuildLogger(middleware.GetZapLogger())
+	util.Log().Info("加载配置, 运行环境: %s", conf.Env)
+
+	// 链路追踪需要在数据库和缓存之前初始化
+	if err := tracing.Init(conf.TracingOptions()); err != nil {
+		util.Log().Panic("链路追踪初始化失败: %v", err)
+	}
 
-	// 读取翻译文件
-	localesPath := findLocalesFile()
-	if err := LoadLocales(localesPath); err != nil {
-		util.Log().Panic("翻译文件加载失败")
+	// 读取翻译目录下所有语言的文件
+	if err := i18n.LoadDir(findLocalesDir()); err != nil {
+		util.Log().Panic("翻译文件加载失败: %v", err)
 	}
 
+	auth.Configure(conf.AuthOptions())
+
 	// 连接数据库
-	model.Database(os.Getenv("MYSQL_DSN"))
-	cache.Redis()
+	model.Database(conf.Database.DSN, model.NewGormLogger(middleware.GetZapLogger().Named("gorm"), time.Duration(conf.Database.SlowThreshold)))
+	if err := audit.Init(conf.AuditOptions()); err != nil {
+		util.Log().Panic("审计日志初始化失败: %v", err)
+	}
+	cache.Init(conf.CacheStore())
+	// 缓存和限流共用 Redis 连接池
+	if store, ok := cache.Default().(*cache.RedisStore); ok {
+		if err := metrics.RegisterRedis("cache", store.Client().PoolStats); err != nil {
+			util.Log().Warning("Redis 连接池指标注册失败: %v", err)
+		}
+		store.Client().AddHook(tracing.RedisHook())
+	}
+	if err := middleware.ConfigureCacheEncoding(conf.CacheEncoding(), conf.Cache.KeyVersion); err != nil {
+		util.Log().Panic("缓存编码配置错误: %v", err)
+	}
+
+	if err := middleware.ConfigureRateLimitFailure(conf.RateLimitFailure()); err != nil {
+		util.Log().Panic("限流配置错误: %v", err)
+	}
+
+	if err := middleware.ConfigureProxies(conf.Proxies()); err != nil {
+		util.Log().Panic("可信代理配置错误: %v", err)
+	}
+
+	if err := middleware.ConfigureGeoIP(conf.IPFilter.GeoIPDB); err != nil {
+		util.Log().Panic("GeoIP 数据库加载失败: %v", err)
+	}
+
+	// 读取路由缓存策略
+	if conf.Cache.PolicyFile == "" {
+		conf.Cache.PolicyFile = findConfigFile([]string{
+			"cache.yaml",
+			"/app/cache.yaml",
+			"internal/config/cache.yaml",
+			"/app/internal/config/cache.yaml",
+		})
+	}
+	if err := middleware.LoadCachePolicies(conf.Cache.PolicyFile); err != nil {
+		util.Log().Panic("缓存策略加载失败: %v", err)
+	}
+
+	// 就绪检查
+	health.Configure(conf.HealthOptions())
+	if err := registerHealthChecks(conf); err != nil {
+		util.Log().Panic("就绪检查注册失败: %v", err)
+	}
+
+	// 可以热更新的配置项, 启动时和每次重新加载后应用, 配置已经校验过不会出错
+	Subscribe(func(c *Config) {
+		if err := middleware.SetLogLevel(c.Log.Level); err != nil {
+			util.Log().Error("日志级别配置错误: %v", err)
+		}
+		if err := middleware.SetPackageLogLevels(c.Log.Packages); err != nil {
+			util.Log().Error("日志级别配置错误: %v", err)
+		}
+		redact.Configure(c.RedactRules())
+	})
+	Subscribe(func(c *Config) {
+		if err := middleware.ConfigureCors(c.Cors.AllowOrigins); err != nil {
+			util.Log().Error("跨域配置错误: %v", err)
+		}
+	})
+	Subscribe(func(c *Config) {
+		if err := middleware.ConfigureRateLimits(c.RateLimitOverrides()); err != nil {
+			util.Log().Error("限流配置错误: %v", err)
+		}
+		middleware.SetRateLimitLegacyHeaders(c.RateLimit.LegacyHeaders)
+	})
+	reloadMu.Lock()
+	publish(conf)
+	reloadMu.Unlock()
+}
+
+// Close 按与 Init 相反的顺序关闭各个模块: 写入队列中的审计事件, 导出尚未发送的 span,
+// 再关闭 Redis 和数据库连接, 最后关闭日志. 需要在 HTTP 服务和后台任务停止之后调用
+func Close(ctx context.Context) error {
+	var errs []error
+	closeStep := func(name string, err error) {
+		if err != nil {
+			util.Log().Warning("%s关闭失败: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	closeStep("审计日志", audit.Close(ctx))
+	closeStep("链路追踪", tracing.Shutdown(ctx))
+	closeStep("缓存", cache.Default().Close())
+	closeStep("数据库", model.Close())
+	util.Log().Info("服务已退出")
+	errs = append(errs, middleware.CloseLogger())
+	return errors.Join(errs...)
 }
 
-// findLocalesFile 查找翻译文件
-func findLocalesFile() string {
-	possiblePaths := []string{
-		"locales/zh-cn.yaml",
-		"/app/locales/zh-cn.yaml",
-		"internal/config/locales/zh-cn.yaml",
-		"/app/internal/config/locales/zh-cn.yaml",
+// registerHealthChecks 注册就绪检查: 数据库、迁移版本、Redis 和输出目录的磁盘空间
+func registerHealthChecks(conf *Config) error {
+	sqlDB, err := model.DB.DB()
+	if err != nil {
+		return err
+	}
+	errs := []error{health.Register(health.Database(sqlDB))}
+	if conf.Health.Migrations {
+		errs = append(errs, health.Register(health.Migrations(sqlDB, model.SchemaVersion)))
+	}
+	if store, ok := cache.Default().(*cache.RedisStore); ok {
+		errs = append(errs, health.Register(health.Redis(store.Client())))
+	}
+	if dirs := conf.OutputDirs(); conf.Health.DiskMinFree > 0 && len(dirs) > 0 {
+		errs = append(errs, health.Register(health.Disk(dirs, uint64(conf.Health.DiskMinFree)<<20)))
 	}
+	return errors.Join(errs...)
+}
+
+// 检查翻译目录是否包含代码中使用的所有 key
+//go:generate go run ../../cmd/i18n -src ../.. -locales locales
+
+// findLocalesDir 查找翻译目录
+func findLocalesDir() string {
+	return findConfigFile([]string{
+		"locales",
+		"/app/locales",
+		"internal/config/locales",
+		"/app/internal/config/locales",
+	})
+}
 
+// findConfigFile 返回第一个存在的配置文件路径
+func findConfigFile(possiblePaths []string) string {
 	for _, path := range possiblePaths {
 		absPath, _ := filepath.Abs(path)
 		if _, err := os.Stat(absPath); err == nil {
@@ -0,0 +1,753 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"openapphub/internal/audit"
+	"openapphub/internal/auth"
+	"openapphub/internal/health"
+	"openapphub/internal/middleware"
+	"openapphub/internal/tracing"
+	"openapphub/pkg/cache"
+	"openapphub/pkg/redact"
+	"openapphub/pkg/secrets"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// 运行环境, 决定加载哪个 profile 配置文件
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// Config 应用配置.
+// 加载顺序: 默认值 -> config.yaml/config.toml -> config.<env>.yaml/toml -> 环境变量(含 .env 文件)
+type Config struct {
+	Env       string          `yaml:"env" toml:"env" json:"env" env:"APP_ENV"`
+	Server    ServerConfig    `yaml:"server" toml:"server" json:"server"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth" json:"auth"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database" json:"database"`
+	Cache     CacheConfig     `yaml:"cache" toml:"cache" json:"cache"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit"`
+	Proxy     ProxyConfig     `yaml:"proxy" toml:"proxy" json:"proxy"`
+	IPFilter  IPFilterConfig  `yaml:"ip_filter" toml:"ip_filter" json:"ip_filter"`
+	Cors      CorsConfig      `yaml:"cors" toml:"cors" json:"cors"`
+	Log       LogConfig       `yaml:"log" toml:"log" json:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics" json:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Audit     AuditConfig     `yaml:"audit" toml:"audit" json:"audit"`
+	Health    HealthConfig    `yaml:"health" toml:"health" json:"health"`
+
+	// sources 实际读取的配置文件, 用于监听文件变化
+	sources []string
+}
+
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Mode string `yaml:"mode" toml:"mode" json:"mode" env:"GIN_MODE"` // debug, release, test
+	Port string `yaml:"port" toml:"port" json:"port" env:"PORT"`
+	// 读取请求、写入响应和空闲连接的超时, 为 0 时不限制
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout" json:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int      `yaml:"max_header_bytes" toml:"max_header_bytes" json:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	// ShutdownDelay 收到 SIGTERM 后就绪检查先失败, 等待这段时间让负载均衡摘除实例, 再停止接收请求
+	ShutdownDelay Duration `yaml:"shutdown_delay" toml:"shutdown_delay" json:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
+	// ShutdownTimeout 等待处理中的请求和后台任务完成的时间, 超时后强制关闭连接
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	Mode                 string   `yaml:"mode" toml:"mode" json:"mode" env:"AUTH_MODE"` // session 或 jwt
+	SessionSecret        string   `yaml:"session_secret" toml:"session_secret" json:"session_secret" env:"SESSION_SECRET" secret:"true"`
+	JWTSecret            string   `yaml:"jwt_secret" toml:"jwt_secret" json:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	JWTExpiration        Duration `yaml:"jwt_expiration" toml:"jwt_expiration" json:"jwt_expiration" env:"JWT_EXPIRATION"`
+	JWTRefreshExpiration Duration `yaml:"jwt_refresh_expiration" toml:"jwt_refresh_expiration" json:"jwt_refresh_expiration" env:"JWT_REFRESH_EXPIRATION"`
+	// AdminToken 管理接口的令牌, 请求需要带上 X-Admin-Token 请求头, 为空时拒绝所有管理接口
+	AdminToken string `yaml:"admin_token" toml:"admin_token" json:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+}
+
+// DatabaseConfig 数据库配置
+type DatabaseConfig struct {
+	DSN string `yaml:"dsn" toml:"dsn" json:"dsn" env:"MYSQL_DSN" secret:"true"`
+	// SlowThreshold 超过该时间的 SQL 以 warn 级别记录, 为 0 时不记录慢查询
+	SlowThreshold Duration `yaml:"slow_threshold" toml:"slow_threshold" json:"slow_threshold" env:"DB_SLOW_THRESHOLD"`
+}
+
+// CacheConfig 缓存后端和缓存条目编码配置
+type CacheConfig struct {
+	Driver            string   `yaml:"driver" toml:"driver" json:"driver" env:"CACHE_DRIVER"` // redis, cluster, sentinel, memory
+	Addrs             []string `yaml:"addrs" toml:"addrs" json:"addrs" env:"REDIS_ADDR"`
+	Password          string   `yaml:"password" toml:"password" json:"password" env:"REDIS_PW" secret:"true"`
+	DB                int      `yaml:"db" toml:"db" json:"db" env:"REDIS_DB"`
+	MasterName        string   `yaml:"master_name" toml:"master_name" json:"master_name" env:"REDIS_MASTER_NAME"`
+	Namespace         string   `yaml:"namespace" toml:"namespace" json:"namespace" env:"CACHE_NAMESPACE"`
+	Codec             string   `yaml:"codec" toml:"codec" json:"codec" env:"CACHE_CODEC"`
+	Compression       string   `yaml:"compression" toml:"compression" json:"compression" env:"CACHE_COMPRESSION"`
+	CompressThreshold int      `yaml:"compress_threshold" toml:"compress_threshold" json:"compress_threshold" env:"CACHE_COMPRESS_THRESHOLD"`
+	KeyVersion        string   `yaml:"key_version" toml:"key_version" json:"key_version" env:"CACHE_KEY_VERSION"`
+	PolicyFile        string   `yaml:"policy_file" toml:"policy_file" json:"policy_file" env:"CACHE_POLICY_FILE"`
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	FailureMode      string   `yaml:"failure_mode" toml:"failure_mode" json:"failure_mode" env:"RATE_LIMIT_FAILURE_MODE"` // open, closed, local
+	BreakerThreshold int      `yaml:"breaker_threshold" toml:"breaker_threshold" json:"breaker_threshold" env:"RATE_LIMIT_BREAKER_THRESHOLD"`
+	BreakerCooldown  Duration `yaml:"breaker_cooldown" toml:"breaker_cooldown" json:"breaker_cooldown" env:"RATE_LIMIT_BREAKER_COOLDOWN"`
+	LegacyHeaders    bool     `yaml:"legacy_headers" toml:"legacy_headers" json:"legacy_headers" env:"RATE_LIMIT_LEGACY_HEADERS"`
+	// Policies 按名称覆盖代码中定义的限流策略, 可以热更新
+	Policies map[string]RateLimitPolicyConfig `yaml:"policies" toml:"policies" json:"policies"`
+}
+
+// RateLimitPolicyConfig 覆盖限流策略的限额, 不配置 limits 或 tiers 时沿用代码中的限额
+type RateLimitPolicyConfig struct {
+	Limits []string            `yaml:"limits" toml:"limits" json:"limits"`
+	Tiers  map[string][]string `yaml:"tiers" toml:"tiers" json:"tiers,omitempty"`
+}
+
+// ProxyConfig 可信代理配置
+type ProxyConfig struct {
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	Headers        []string `yaml:"headers" toml:"headers" json:"headers" env:"TRUSTED_PROXY_HEADERS"`
+	ProxyProtocol  bool     `yaml:"proxy_protocol" toml:"proxy_protocol" json:"proxy_protocol" env:"PROXY_PROTOCOL"`
+}
+
+// IPFilterConfig IP 黑白名单和国家规则
+type IPFilterConfig struct {
+	Denylist             []string `yaml:"denylist" toml:"denylist" json:"denylist" env:"IP_DENYLIST"`
+	AdminAllowedNetworks []string `yaml:"admin_allowed_networks" toml:"admin_allowed_networks" json:"admin_allowed_networks" env:"ADMIN_ALLOWED_NETWORKS"`
+	GeoIPDB              string   `yaml:"geoip_db" toml:"geoip_db" json:"geoip_db" env:"GEOIP_DB"`
+	DenyCountries        []string `yaml:"deny_countries" toml:"deny_countries" json:"deny_countries" env:"GEO_DENY_COUNTRIES"`
+}
+
+// CorsConfig 跨域配置
+type CorsConfig struct {
+	// AllowOrigins 允许跨域的域名, 为空时生产环境只允许 http://www.example.com, 其它环境允许本地地址
+	AllowOrigins []string `yaml:"allow_origins" toml:"allow_origins" json:"allow_origins" env:"CORS_ALLOW_ORIGINS"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level    string            `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`             // debug, info, warn, error
+	Encoding string            `yaml:"encoding" toml:"encoding" json:"encoding" env:"LOG_ENCODING"` // json 或 console
+	Outputs  []string          `yaml:"outputs" toml:"outputs" json:"outputs" env:"LOG_OUTPUTS"`     // stdout、stderr 或文件路径
+	Rotation LogRotationConfig `yaml:"rotation" toml:"rotation" json:"rotation"`
+	Sampling LogSamplingConfig `yaml:"sampling" toml:"sampling" json:"sampling"`
+	// Packages 按包名或 logger 名称覆盖日志级别, 环境变量格式为 middleware=debug,access=warn
+	Packages map[string]string `yaml:"packages" toml:"packages" json:"packages,omitempty" env:"LOG_PACKAGES"`
+	Redact   LogRedactConfig   `yaml:"redact" toml:"redact" json:"redact"`
+}
+
+// LogRedactConfig 在默认规则(密码、令牌、Authorization、Cookie、DSN 等)之外需要在日志中隐藏的字段和请求头
+type LogRedactConfig struct {
+	// Fields 字段名包含其中任一项时隐藏, 适用于请求体、查询参数、日志字段和 SQL 参数
+	Fields  []string `yaml:"fields" toml:"fields" json:"fields" env:"LOG_REDACT_FIELDS"`
+	Headers []string `yaml:"headers" toml:"headers" json:"headers" env:"LOG_REDACT_HEADERS"`
+}
+
+// LogRotationConfig 日志文件轮转
+type LogRotationConfig struct {
+	MaxSize    int      `yaml:"max_size" toml:"max_size" json:"max_size" env:"LOG_MAX_SIZE"` // 单位 MB
+	MaxBackups int      `yaml:"max_backups" toml:"max_backups" json:"max_backups" env:"LOG_MAX_BACKUPS"`
+	MaxAge     Duration `yaml:"max_age" toml:"max_age" json:"max_age" env:"LOG_MAX_AGE"` // 按天计算, 不足一天按一天
+	Compress   bool     `yaml:"compress" toml:"compress" json:"compress" env:"LOG_COMPRESS"`
+}
+
+// LogSamplingConfig 日志采样, initial 为 0 时不采样
+type LogSamplingConfig struct {
+	Initial    int `yaml:"initial" toml:"initial" json:"initial" env:"LOG_SAMPLING_INITIAL"`
+	Thereafter int `yaml:"thereafter" toml:"thereafter" json:"thereafter" env:"LOG_SAMPLING_THEREAFTER"`
+}
+
+// MetricsConfig Prometheus 指标接口配置
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" json:"enabled" env:"METRICS_ENABLED"`
+	Path    string `yaml:"path" toml:"path" json:"path" env:"METRICS_PATH"`
+	// Listen 单独监听的地址, 例如 127.0.0.1:9090; 为空时由主服务输出, 只允许管理网段访问
+	Listen string `yaml:"listen" toml:"listen" json:"listen" env:"METRICS_LISTEN"`
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	// Exporter none, otlp, stdout 或 file; none 时只传递上游的 traceparent, 不导出 span
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint OTLP/HTTP 地址, 例如 http://otel-collector:4318; 为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"TRACING_ENDPOINT"`
+	File        string  `yaml:"file" toml:"file" json:"file" env:"TRACING_FILE"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" json:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" toml:"service_name" json:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled" env:"AUDIT_ENABLED"`
+	// File 同时按行写入 JSON 的文件, 为空时只写入 audit_events 表
+	File      string `yaml:"file" toml:"file" json:"file" env:"AUDIT_FILE"`
+	QueueSize int    `yaml:"queue_size" toml:"queue_size" json:"queue_size" env:"AUDIT_QUEUE_SIZE"`
+}
+
+// HealthConfig 就绪检查(/readyz)配置
+type HealthConfig struct {
+	Timeout  Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"HEALTH_TIMEOUT"` // 每项检查的超时
+	CacheTTL Duration `yaml:"cache_ttl" toml:"cache_ttl" json:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+	// Migrations 是否检查数据库迁移版本, 不使用 database/migrations 建表时关闭
+	Migrations bool `yaml:"migrations" toml:"migrations" json:"migrations" env:"HEALTH_CHECK_MIGRATIONS"`
+	// DiskMinFree 日志等输出文件所在目录至少需要的可用空间, 单位 MB, 0 表示不检查
+	DiskMinFree int `yaml:"disk_min_free" toml:"disk_min_free" json:"disk_min_free" env:"HEALTH_DISK_MIN_FREE"`
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			Mode:              "debug",
+			Port:              "3000",
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			WriteTimeout:      Duration(60 * time.Second),
+			IdleTimeout:       Duration(120 * time.Second),
+			MaxHeaderBytes:    1 << 20,
+			ShutdownDelay:     Duration(5 * time.Second),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Auth: AuthConfig{
+			Mode:                 auth.ModeSession,
+			JWTExpiration:        Duration(15 * time.Minute),
+			JWTRefreshExpiration: Duration(7 * 24 * time.Hour),
+		},
+		Cache: CacheConfig{
+			Driver:            cache.DriverRedis,
+			Addrs:             []string{"127.0.0.1:6379"},
+			Codec:             cache.CodecGob,
+			Compression:       cache.CompressionNone,
+			CompressThreshold: 1024,
+			KeyVersion:        "v1",
+		},
+		RateLimit: RateLimitConfig{
+			FailureMode:      middleware.FailLocal,
+			BreakerThreshold: 5,
+			BreakerCooldown:  Duration(30 * time.Second),
+			LegacyHeaders:    true,
+		},
+		Proxy: ProxyConfig{
+			Headers: []string{middleware.HeaderXForwardedFor, middleware.HeaderXRealIP},
+		},
+		IPFilter: IPFilterConfig{
+			AdminAllowedNetworks: []string{"127.0.0.0/8", "::1", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+		},
+		Database: DatabaseConfig{SlowThreshold: Duration(time.Second)},
+		Metrics:  MetricsConfig{Enabled: true, Path: "/metrics"},
+		Audit:    AuditConfig{Enabled: true, QueueSize: 1024},
+		Health: HealthConfig{
+			Timeout:     Duration(2 * time.Second),
+			CacheTTL:    Duration(5 * time.Second),
+			Migrations:  true,
+			DiskMinFree: 100,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			File:        "./logs/traces.json",
+			SampleRatio: 1,
+			ServiceName: "openapphub",
+		},
+		Log: LogConfig{
+			Level:    "info",
+			Encoding: middleware.LogEncodingJSON,
+			Outputs:  []string{middleware.LogOutputStdout, "./logs/app.log"},
+			Rotation: LogRotationConfig{MaxSize: 500, MaxBackups: 3, MaxAge: Duration(28 * 24 * time.Hour), Compress: true},
+		},
+	}
+}
+
+// Load 按加载顺序读取并校验配置, 所有错误一次性返回.
+// 带 env 标签的配置项都可以用 <KEY>_FILE 指定从文件读取, 密钥还可以是 ENC[...] 或 ref+vault://... 引用, 见 secrets.go
+func Load() (*Config, error) {
+	// 从本地读取环境变量, 不覆盖已经存在的环境变量
+	_ = godotenv.Load()
+
+	conf := Default()
+	if env := os.Getenv("APP_ENV"); env != "" {
+		conf.Env = env
+	}
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		path = findConfigFile([]string{
+			"config.yaml", "config.toml",
+			"/app/config.yaml", "/app/config.toml",
+			"internal/config/config.yaml", "internal/config/config.toml",
+		})
+	}
+	if path != "" {
+		if err := conf.loadFile(path); err != nil {
+			return nil, err
+		}
+		conf.sources = append(conf.sources, path)
+		// 配置文件中可以指定运行环境, 但环境变量优先
+		if env := os.Getenv("APP_ENV"); env != "" {
+			conf.Env = env
+		}
+		// profile 文件不存在时也加入监听列表, 以便新建后生效
+		profile := profilePath(path, conf.Env)
+		if err := conf.loadFile(profile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		conf.sources = append(conf.sources, profile)
+	}
+
+	if err := applyEnv(reflect.ValueOf(conf).Elem(), ""); err != nil {
+		return nil, err
+	}
+	if err := configureSecrets(); err != nil {
+		return nil, err
+	}
+	if err := resolveSecrets(reflect.ValueOf(conf).Elem(), ""); err != nil {
+		return nil, err
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// profilePath 返回运行环境对应的配置文件, 例如 config.production.yaml
+func profilePath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+// loadFile 读取配置文件并覆盖文件中出现的字段, 不允许出现未知字段
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, c)
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		err = fmt.Errorf("unsupported config format")
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 用 env 标签指定的环境变量覆盖配置, 空值视为未设置
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name := prefix + field.Tag.Get("yaml")
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value, name+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		raw := strings.TrimSpace(os.Getenv(key))
+		if raw == "" {
+			// <KEY>_FILE 指向挂载的文件, 例如 Docker/Kubernetes secrets
+			path := os.Getenv(key + "_FILE")
+			if path == "" {
+				continue
+			}
+			var err error
+			if raw, err = secrets.ReadFile(path); err != nil {
+				return fmt.Errorf("%s (%s_FILE): %w", name, key, err)
+			}
+			key += "_FILE"
+		}
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("%s (%s): %w", name, key, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case Duration:
+		d, err := ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(d))
+		return nil
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	case map[string]string:
+		items := make(map[string]string)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid item %q, want key=value", item)
+			}
+			items[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// Validate 校验配置, 返回所有不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env == EnvDevelopment || c.Env == EnvTest || c.Env == EnvProduction,
+		"env: must be one of development, test, production, got %q", c.Env)
+	check(c.Server.Mode == "debug" || c.Server.Mode == "release" || c.Server.Mode == "test",
+		"server.mode: must be one of debug, release, test, got %q", c.Server.Mode)
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port: invalid port %q", c.Server.Port)
+	check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout: must not be negative")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout: must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout: must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout: must not be negative")
+	check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes: must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay: must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+
+	switch c.Auth.Mode {
+	case auth.ModeSession:
+		check(c.Auth.SessionSecret != "", "auth.session_secret: required when auth.mode is session (SESSION_SECRET)")
+	case auth.ModeJWT:
+		check(c.Auth.JWTSecret != "", "auth.jwt_secret: required when auth.mode is jwt (JWT_SECRET)")
+	default:
+		check(false, "auth.mode: must be session or jwt, got %q", c.Auth.Mode)
+	}
+	check(c.Auth.JWTExpiration > 0, "auth.jwt_expiration: must be positive")
+	check(c.Auth.JWTRefreshExpiration > c.Auth.JWTExpiration,
+		"auth.jwt_refresh_expiration: must be longer than auth.jwt_expiration")
+	if c.Env == EnvProduction {
+		secret := c.Auth.SessionSecret
+		if c.Auth.Mode == auth.ModeJWT {
+			secret = c.Auth.JWTSecret
+		}
+		check(len(secret) >= 32, "auth: the %s secret must be at least 32 characters in production", c.Auth.Mode)
+		check(c.Auth.AdminToken == "" || len(c.Auth.AdminToken) >= 32, "auth.admin_token: must be at least 32 characters in production")
+		check(c.Server.Mode == "release", "server.mode: must be release in production")
+	}
+
+	check(c.Database.DSN != "", "database.dsn: required (MYSQL_DSN)")
+	check(c.Database.SlowThreshold >= 0, "database.slow_threshold: must not be negative")
+
+	switch c.Cache.Driver {
+	case cache.DriverMemory:
+	case cache.DriverRedis, cache.DriverCluster, cache.DriverSentinel:
+		check(len(c.Cache.Addrs) > 0, "cache.addrs: required for the %s driver (REDIS_ADDR)", c.Cache.Driver)
+		check(c.Cache.Driver != cache.DriverSentinel || c.Cache.MasterName != "",
+			"cache.master_name: required for the sentinel driver (REDIS_MASTER_NAME)")
+	default:
+		check(false, "cache.driver: must be one of redis, cluster, sentinel, memory, got %q", c.Cache.Driver)
+	}
+	if err := c.CacheEncoding().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("cache: %w", err))
+	}
+	check(c.Cache.CompressThreshold >= 0, "cache.compress_threshold: must not be negative")
+
+	check(c.RateLimit.FailureMode == middleware.FailOpen || c.RateLimit.FailureMode == middleware.FailClosed ||
+		c.RateLimit.FailureMode == middleware.FailLocal,
+		"rate_limit.failure_mode: must be one of open, closed, local, got %q", c.RateLimit.FailureMode)
+	check(c.RateLimit.BreakerThreshold > 0, "rate_limit.breaker_threshold: must be positive")
+	check(c.RateLimit.BreakerCooldown > 0, "rate_limit.breaker_cooldown: must be positive")
+
+	for _, network := range c.Proxy.TrustedProxies {
+		check(validNetwork(network), "proxy.trusted_proxies: invalid IP or CIDR %q", network)
+	}
+	for _, network := range c.IPFilter.Denylist {
+		check(validNetwork(network), "ip_filter.denylist: invalid IP or CIDR %q", network)
+	}
+	// 空的允许列表表示允许所有地址, 管理接口不能对所有地址开放
+	check(len(c.IPFilter.AdminAllowedNetworks) > 0,
+		"ip_filter.admin_allowed_networks: must not be empty, an empty list would expose admin routes to every address")
+	for _, network := range c.IPFilter.AdminAllowedNetworks {
+		check(validNetwork(network), "ip_filter.admin_allowed_networks: invalid IP or CIDR %q", network)
+	}
+	check(len(c.IPFilter.DenyCountries) == 0 || c.IPFilter.GeoIPDB != "",
+		"ip_filter.geoip_db: required when ip_filter.deny_countries is set (GEOIP_DB)")
+
+	for name, policy := range c.RateLimit.Policies {
+		// 空列表会关闭限流, 不配置 limits 时沿用代码中的限额
+		check(policy.Limits == nil || len(policy.Limits) > 0,
+			"rate_limit.policies.%s.limits: must not be empty, omit it to keep the limits defined in code", name)
+		if err := middleware.ValidateRateLimits(policy.Limits); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.policies.%s: %w", name, err))
+		}
+		for tier, limits := range policy.Tiers {
+			check(len(limits) > 0, "rate_limit.policies.%s.tiers.%s: must not be empty", name, tier)
+			if err := middleware.ValidateRateLimits(limits); err != nil {
+				errs = append(errs, fmt.Errorf("rate_limit.policies.%s.tiers.%s: %w", name, tier, err))
+			}
+		}
+	}
+	if len(c.Cors.AllowOrigins) > 0 {
+		if err := middleware.ValidateCorsOrigins(c.Cors.AllowOrigins); err != nil {
+			errs = append(errs, fmt.Errorf("cors.allow_origins: %w", err))
+		}
+	}
+	// 日志配置的每一项错误单独报告, 与其它配置项一致
+	if err, ok := middleware.ValidateLogOptions(c.LogOptions()).(interface{ Unwrap() []error }); ok {
+		for _, e := range err.Unwrap() {
+			errs = append(errs, fmt.Errorf("log.%w", e))
+		}
+	}
+	check(c.Log.Rotation.MaxAge >= 0, "log.rotation.max_age: must not be negative")
+
+	if c.Metrics.Enabled {
+		check(strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path: must start with /, got %q", c.Metrics.Path)
+		if c.Metrics.Listen != "" {
+			_, _, err := net.SplitHostPort(c.Metrics.Listen)
+			check(err == nil, "metrics.listen: must be host:port, got %q", c.Metrics.Listen)
+			check(c.Metrics.Listen != ":"+c.Server.Port, "metrics.listen: must differ from the server port")
+		}
+	}
+
+	check(!c.Audit.Enabled || c.Audit.QueueSize > 0, "audit.queue_size: must be positive, got %d", c.Audit.QueueSize)
+	check(c.Health.Timeout > 0, "health.timeout: must be positive")
+	check(c.Health.CacheTTL >= 0, "health.cache_ttl: must not be negative")
+	check(c.Health.DiskMinFree >= 0, "health.disk_min_free: must not be negative")
+
+	if err, ok := tracing.ValidateOptions(c.TracingOptions()).(interface{ Unwrap() []error }); ok {
+		for _, e := range err.Unwrap() {
+			errs = append(errs, fmt.Errorf("tracing.%w", e))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validNetwork(value string) bool {
+	if strings.Contains(value, "/") {
+		_, err := netip.ParsePrefix(value)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(value)
+	return err == nil
+}
+
+// Redacted 返回隐藏了密钥(带 secret 标签的字段)的配置副本, 用于输出日志和管理接口
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	_ = walkSecrets(reflect.ValueOf(&redacted).Elem(), "", func(_ string, value reflect.Value) error {
+		if value.String() != "" {
+			value.SetString(secrets.Redacted)
+		}
+		return nil
+	})
+	return &redacted
+}
+
+// CacheStore 缓存后端配置
+func (c *Config) CacheStore() cache.Config {
+	return cache.Config{
+		Driver:     c.Cache.Driver,
+		Addrs:      c.Cache.Addrs,
+		Password:   c.Cache.Password,
+		DB:         c.Cache.DB,
+		MasterName: c.Cache.MasterName,
+		Namespace:  c.Cache.Namespace,
+	}
+}
+
+// CacheEncoding 缓存条目编码配置
+func (c *Config) CacheEncoding() cache.EntryEncoder {
+	return cache.EntryEncoder{
+		Codec:             c.Cache.Codec,
+		Compression:       c.Cache.Compression,
+		CompressThreshold: c.Cache.CompressThreshold,
+	}
+}
+
+// Proxies 可信代理配置, 需要先通过 Validate 校验
+func (c *Config) Proxies() middleware.ProxyConfig {
+	conf := middleware.ProxyConfig{
+		Headers:       slices.Clone(c.Proxy.Headers),
+		ProxyProtocol: c.Proxy.ProxyProtocol,
+	}
+	for _, network := range c.Proxy.TrustedProxies {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			addr := netip.MustParseAddr(network).Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		conf.TrustedProxies = append(conf.TrustedProxies, prefix)
+	}
+	return conf
+}
+
+// RateLimitFailure 限流降级配置
+func (c *Config) RateLimitFailure() middleware.RateLimitFailureConfig {
+	return middleware.RateLimitFailureConfig{
+		Mode:             c.RateLimit.FailureMode,
+		BreakerThreshold: c.RateLimit.BreakerThreshold,
+		BreakerCooldown:  time.Duration(c.RateLimit.BreakerCooldown),
+	}
+}
+
+// RateLimitOverrides 按名称覆盖的限流策略
+func (c *Config) RateLimitOverrides() map[string]middleware.RateLimitOverride {
+	overrides := make(map[string]middleware.RateLimitOverride, len(c.RateLimit.Policies))
+	for name, policy := range c.RateLimit.Policies {
+		overrides[name] = middleware.RateLimitOverride{Limits: policy.Limits, Tiers: policy.Tiers}
+	}
+	return overrides
+}
+
+// LogOptions 日志配置
+func (c *Config) LogOptions() middleware.LogOptions {
+	return middleware.LogOptions{
+		Level:    c.Log.Level,
+		Encoding: c.Log.Encoding,
+		Outputs:  slices.Clone(c.Log.Outputs),
+		Rotation: middleware.LogRotation{
+			MaxSize:    c.Log.Rotation.MaxSize,
+			MaxBackups: c.Log.Rotation.MaxBackups,
+			MaxAge:     int((time.Duration(c.Log.Rotation.MaxAge) + 24*time.Hour - 1) / (24 * time.Hour)),
+			Compress:   c.Log.Rotation.Compress,
+		},
+		Sampling: middleware.LogSampling{
+			Initial:    c.Log.Sampling.Initial,
+			Thereafter: c.Log.Sampling.Thereafter,
+		},
+		Packages: maps.Clone(c.Log.Packages),
+	}
+}
+
+// RedactRules 日志中隐藏敏感数据的规则
+func (c *Config) RedactRules() redact.Rules {
+	return redact.Rules{
+		Fields:  slices.Clone(c.Log.Redact.Fields),
+		Headers: slices.Clone(c.Log.Redact.Headers),
+	}
+}
+
+// TracingOptions 链路追踪配置
+func (c *Config) TracingOptions() tracing.Options {
+	return tracing.Options{
+		Exporter:    c.Tracing.Exporter,
+		Endpoint:    c.Tracing.Endpoint,
+		File:        c.Tracing.File,
+		SampleRatio: c.Tracing.SampleRatio,
+		ServiceName: c.Tracing.ServiceName,
+	}
+}
+
+// AuditOptions 审计日志配置
+func (c *Config) AuditOptions() audit.Options {
+	return audit.Options{
+		Enabled:   c.Audit.Enabled,
+		File:      c.Audit.File,
+		QueueSize: c.Audit.QueueSize,
+	}
+}
+
+// HealthOptions 就绪检查的超时和缓存时间
+func (c *Config) HealthOptions() health.Options {
+	return health.Options{
+		Timeout:  time.Duration(c.Health.Timeout),
+		CacheTTL: time.Duration(c.Health.CacheTTL),
+	}
+}
+
+// OutputDirs 日志、审计日志和链路追踪输出文件所在的目录, 用于检查磁盘空间
+func (c *Config) OutputDirs() []string {
+	var files []string
+	for _, output := range c.Log.Outputs {
+		if output != middleware.LogOutputStdout && output != middleware.LogOutputStderr {
+			files = append(files, output)
+		}
+	}
+	if c.Audit.Enabled && c.Audit.File != "" {
+		files = append(files, c.Audit.File)
+	}
+	if c.Tracing.Exporter == tracing.ExporterFile {
+		files = append(files, c.Tracing.File)
+	}
+	var dirs []string
+	for _, file := range files {
+		if dir := filepath.Dir(file); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// AuthOptions 认证配置
+func (c *Config) AuthOptions() auth.Config {
+	return auth.Config{
+		Mode:              c.Auth.Mode,
+		Secret:            c.Auth.JWTSecret,
+		Expiration:        time.Duration(c.Auth.JWTExpiration),
+		RefreshExpiration: time.Duration(c.Auth.JWTRefreshExpiration),
+	}
+}
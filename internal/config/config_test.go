@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"openapphub/internal/util"
+	"openapphub/pkg/secrets"
+
+	"go.uber.org/zap"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"7d":     7 * 24 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"15m":    15 * time.Minute,
+		" 30s ":  30 * time.Second,
+		"0d":     0,
+		"2d30m":  48*time.Hour + 30*time.Minute,
+		"1h30m":  90 * time.Minute,
+		"10d1ms": 10*24*time.Hour + time.Millisecond,
+	}
+	for input, want := range cases {
+		got, err := ParseDuration(input)
+		if err != nil || time.Duration(got) != want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"7", "d", "-1d", "7days", "1h1d"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", input)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	path := write("config.yaml", "env: test\nauth:\n  jwt_refresh_expiration: 3d\ncache:\n  driver: memory\n")
+	write("config.test.yaml", "server:\n  port: \"4000\"\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("APP_ENV", "")
+	t.Setenv("SESSION_SECRET", "secret")
+	t.Setenv("MYSQL_DSN", "user@tcp(localhost)/db")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Env != EnvTest || conf.Server.Port != "4000" || conf.Cache.Driver != "memory" {
+		t.Errorf("file and profile not applied: %+v", conf)
+	}
+	if time.Duration(conf.Auth.JWTRefreshExpiration) != 72*time.Hour {
+		t.Errorf("refresh expiration = %v", conf.Auth.JWTRefreshExpiration)
+	}
+	if len(conf.Proxies().TrustedProxies) != 2 {
+		t.Errorf("trusted proxies = %v", conf.Proxies().TrustedProxies)
+	}
+
+	t.Setenv("JWT_EXPIRATION", "7days")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "JWT_EXPIRATION") {
+		t.Errorf("expected invalid duration error, got %v", err)
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	conf := Default()
+	conf.Env = EnvProduction
+	conf.Cache.Codec = "xml"
+	conf.Tracing.Exporter = "zipkin"
+	conf.IPFilter.AdminAllowedNetworks = nil
+	conf.RateLimit.Policies = map[string]RateLimitPolicyConfig{"api": {Limits: []string{}}}
+
+	err := conf.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"auth.session_secret", "database.dsn", "server.mode", "cache: unknown cache codec", "tracing.exporter", "ip_filter.admin_allowed_networks", "rate_limit.policies.api.limits"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	util.BuildLogger(zap.NewNop())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	policyPath := filepath.Join(dir, "cache.yaml")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(path, "cors:\n  allow_origins: [https://a.example.com]\n")
+	write(policyPath, "routes: []\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("SESSION_SECRET", "secret")
+	t.Setenv("MYSQL_DSN", "user:pass@tcp(localhost)/db")
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Cache.PolicyFile = policyPath
+	current.Store(conf)
+	var notified []*Config
+	Subscribe(func(c *Config) { notified = append(notified, c) })
+
+	// 不合法的修改被拒绝, 保留原有配置
+	write(path, "rate_limit:\n  policies:\n    login: {limits: [10-X]}\n")
+	if err := Reload(); err == nil || !strings.Contains(err.Error(), "rate_limit.policies.login") {
+		t.Fatalf("expected invalid rate limit error, got %v", err)
+	}
+	if Current() != conf || len(notified) != 0 {
+		t.Fatal("invalid config should not be applied")
+	}
+
+	// 可以热更新的配置立即生效, 其它配置需要重启
+	write(path, "server:\n  port: \"4000\"\ncors:\n  allow_origins: [https://b.example.com]\n")
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	next := Current()
+	if len(notified) != 1 || notified[0] != next {
+		t.Fatalf("subscriber not notified: %v", notified)
+	}
+	if next.Cors.AllowOrigins[0] != "https://b.example.com" || next.Server.Port != conf.Server.Port {
+		t.Errorf("unexpected effective config: %+v", next)
+	}
+
+	redacted := next.Redacted()
+	if redacted.Database.DSN != secrets.Redacted || redacted.Auth.SessionSecret != secrets.Redacted || next.Auth.SessionSecret != "secret" {
+		t.Errorf("secrets not redacted: %+v", redacted.Auth)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := secrets.GenerateKey()
+	encrypted, err := secrets.Encrypt(key, "user:db-password@tcp(localhost)/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretFile := filepath.Join(dir, "session_secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", configFile)
+	t.Setenv("SESSION_SECRET", "")
+	t.Setenv("SESSION_SECRET_FILE", secretFile)
+	t.Setenv("MYSQL_DSN", encrypted)
+	t.Setenv("SECRETS_KEY", key)
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Auth.SessionSecret != "from-file" || conf.Database.DSN != "user:db-password@tcp(localhost)/db" {
+		t.Errorf("secrets not resolved: %q %q", conf.Auth.SessionSecret, conf.Database.DSN)
+	}
+	if got := secrets.Mask("access denied for db-password"); strings.Contains(got, "db-password") {
+		t.Errorf("database password not masked: %q", got)
+	}
+
+	t.Setenv("SECRETS_KEY", "")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "database.dsn") {
+		t.Errorf("expected a decryption error for database.dsn, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration 配置文件中的时长, 在 time.ParseDuration 的基础上支持天(d), 例如 "7d"、"1d12h"
+type Duration time.Duration
+
+// ParseDuration 解析时长, 格式错误时返回包含原始值的错误
+func ParseDuration(value string) (Duration, error) {
+	s := strings.TrimSpace(value)
+	var days int64
+	if i := strings.Index(s, "d"); i >= 0 {
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		days, s = n, s[i+1:]
+	}
+	var d time.Duration
+	if s != "" {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	return Duration(time.Duration(days)*24*time.Hour + d), nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText 输出为 time.Duration 的格式, 例如 168h0m0s
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText 用于 TOML 配置文件
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalYAML 用于 YAML 配置文件
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(text))
+}
@@ -0,0 +1,11 @@
+package config
+
+import (
+	"testing"
+
+	"openapphub/pkg/i18n/i18ntest"
+)
+
+func TestLocalesComplete(t *testing.T) {
+	i18ntest.AssertComplete(t, "locales", "../..")
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"openapphub/internal/middleware"
+	"openapphub/internal/util"
+)
+
+// Subscriber 在配置重新加载后调用, 只应读取可以热更新的配置项
+type Subscriber func(conf *Config)
+
+var (
+	current atomic.Pointer[Config]
+
+	reloadMu    sync.Mutex
+	subscribers []Subscriber
+)
+
+// Current 返回当前生效的配置, 调用方不能修改返回值
+func Current() *Config {
+	return current.Load()
+}
+
+// Subscribe 注册配置变化的回调, 按注册顺序调用
+func Subscribe(fn Subscriber) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Reload 重新读取配置文件和环境变量, 原子替换可以热更新的配置项并通知订阅者.
+// 新配置不合法时返回错误并保留原有配置.
+// 可以热更新的配置: cors、log、rate_limit.policies、rate_limit.legacy_headers 以及缓存策略文件的内容,
+// 其它配置项的变化需要重启后生效
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	running := current.Load()
+	if running == nil {
+		return fmt.Errorf("config is not initialized")
+	}
+	loaded, err := Load()
+	if err != nil {
+		return err
+	}
+	// 缓存策略文件同样先解析, 任何一项不合法都不做修改
+	policies, err := os.ReadFile(running.Cache.PolicyFile)
+	if err != nil {
+		return err
+	}
+	if _, err := middleware.ParseCachePolicies(policies); err != nil {
+		return fmt.Errorf("%s: %w", running.Cache.PolicyFile, err)
+	}
+
+	next := *running
+	next.sources = loaded.sources
+	next.Cors = loaded.Cors
+	// 日志的编码和输出在启动时确定, 只有日志级别和隐藏规则可以热更新
+	next.Log.Level = loaded.Log.Level
+	next.Log.Packages = loaded.Log.Packages
+	next.Log.Redact = loaded.Log.Redact
+	next.RateLimit.Policies = loaded.RateLimit.Policies
+	next.RateLimit.LegacyHeaders = loaded.RateLimit.LegacyHeaders
+
+	loaded.Cache.PolicyFile = running.Cache.PolicyFile
+	if changed := changedSections(&next, loaded); len(changed) > 0 {
+		util.Log().Warning("配置项 %v 的修改需要重启后生效", changed)
+	}
+
+	if err := middleware.LoadCachePolicies(running.Cache.PolicyFile); err != nil {
+		return err
+	}
+	publish(&next)
+	util.Log().Info("配置已重新加载")
+	return nil
+}
+
+// publish 替换当前配置并通知所有订阅者, 调用方需持有 reloadMu
+func publish(conf *Config) {
+	current.Store(conf)
+	for _, fn := range subscribers {
+		fn(conf)
+	}
+}
+
+// changedSections 返回两份配置中不同的顶层配置项
+func changedSections(a, b *Config) []string {
+	var changed []string
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, field.Tag.Get("yaml"))
+		}
+	}
+	return changed
+}
+
+// Watch 在收到 SIGHUP 或配置文件、缓存策略文件发生变化时重新加载配置, ctx 结束时停止
+func Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stamps := fileStamps(Current())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			util.Log().Info("收到 SIGHUP, 重新加载配置")
+		case <-ticker.C:
+			latest := fileStamps(Current())
+			if reflect.DeepEqual(latest, stamps) {
+				continue
+			}
+			stamps = latest
+			util.Log().Info("配置文件发生变化, 重新加载配置")
+		}
+		if err := Reload(); err != nil {
+			util.Log().Error("配置重新加载失败, 继续使用原有配置: %v", err)
+		}
+		stamps = fileStamps(Current())
+	}
+}
+
+// fileStamps 返回配置文件的修改时间和大小, 不存在的文件为空字符串
+func fileStamps(conf *Config) map[string]string {
+	paths := append([]string{conf.Cache.PolicyFile}, conf.sources...)
+	stamps := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+		} else {
+			stamps[path] = ""
+		}
+	}
+	return stamps
+}
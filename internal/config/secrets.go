@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"openapphub/pkg/secrets"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// secretsTimeout 从外部密钥服务读取所有密钥的超时时间
+const secretsTimeout = 30 * time.Second
+
+// configureSecrets 按环境变量配置解密密钥和外部密钥服务, 这些配置本身不能写在配置文件中.
+//
+//	SECRETS_KEY / SECRETS_KEY_FILE   解密 ENC[...] 的本地密钥, 用 go run ./cmd/secrets keygen 生成
+//	VAULT_ADDR                       Vault 地址, 设置后可以使用 ref+vault://<path>#<key>
+//	VAULT_TOKEN / VAULT_TOKEN_FILE   Vault 访问令牌
+//	VAULT_NAMESPACE                  Vault Enterprise 命名空间
+func configureSecrets() error {
+	key, err := envOrFile("SECRETS_KEY")
+	if err != nil {
+		return err
+	}
+	if err := secrets.SetKey(key); err != nil {
+		return fmt.Errorf("SECRETS_KEY: %w", err)
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil
+	}
+	token, err := envOrFile("VAULT_TOKEN")
+	if err != nil {
+		return err
+	}
+	secrets.RegisterProvider("vault", secrets.NewVault(addr, token, os.Getenv("VAULT_NAMESPACE")))
+	return nil
+}
+
+// resolveSecrets 解析带 secret 标签的配置项中的密钥引用, 并记录密钥以便在日志中隐藏
+func resolveSecrets(v reflect.Value, prefix string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+	return walkSecrets(v, prefix, func(name string, value reflect.Value) error {
+		resolved, err := secrets.Resolve(ctx, value.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		value.SetString(resolved)
+		if name == "database.dsn" && resolved != "" {
+			// 连接串中的密码可能单独出现在日志中
+			if dsn, err := mysql.ParseDSN(resolved); err == nil {
+				secrets.Track(dsn.Passwd)
+			}
+		}
+		return nil
+	})
+}
+
+func walkSecrets(v reflect.Value, prefix string, fn func(name string, value reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name := prefix + field.Tag.Get("yaml")
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			if err := walkSecrets(value, name+".", fn); err != nil {
+				return err
+			}
+		case field.Tag.Get("secret") == "true":
+			if err := fn(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// envOrFile 读取环境变量, 为空时读取 <key>_FILE 指向的文件
+func envOrFile(key string) (string, error) {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value, nil
+	}
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	value, err := secrets.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Database 检查数据库连接, 并输出连接池的使用情况
+func Database(db *sql.DB) Check {
+	return Check{
+		Name: "database",
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			err := db.PingContext(ctx)
+			stats := db.Stats()
+			return map[string]interface{}{
+				"open_connections": stats.OpenConnections,
+				"in_use":           stats.InUse,
+				"idle":             stats.Idle,
+				"max_open":         stats.MaxOpenConnections,
+				"wait_count":       stats.WaitCount,
+			}, err
+		},
+	}
+}
+
+// Redis 检查 Redis 连接, 并输出连接池的使用情况
+func Redis(client redis.UniversalClient) Check {
+	return Check{
+		Name: "redis",
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			err := client.Ping(ctx).Err()
+			stats := client.PoolStats()
+			return map[string]interface{}{
+				"total_connections": stats.TotalConns,
+				"idle_connections":  stats.IdleConns,
+				"timeouts":          stats.Timeouts,
+			}, err
+		},
+	}
+}
+
+// Migrations 检查 golang-migrate 记录的迁移版本不低于 expected, 并且上次迁移没有中断(dirty)
+func Migrations(db *sql.DB, expected uint) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			var (
+				version uint
+				dirty   bool
+			)
+			err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+			if errors.Is(err, sql.ErrNoRows) {
+				return map[string]interface{}{"expected": expected}, errors.New("no migration has been applied")
+			}
+			if err != nil {
+				return nil, err
+			}
+			details := map[string]interface{}{"version": version, "expected": expected, "dirty": dirty}
+			switch {
+			case dirty:
+				return details, fmt.Errorf("migration %d failed and left the schema dirty", version)
+			case version < expected:
+				return details, fmt.Errorf("schema version %d is older than %d", version, expected)
+			}
+			return details, nil
+		},
+	}
+}
+
+// Disk 检查目录所在的文件系统至少有 minFree 字节的可用空间, 用于日志等文件的输出目录
+func Disk(dirs []string, minFree uint64) Check {
+	return Check{
+		Name: "disk",
+		Run: func(ctx context.Context) (map[string]interface{}, error) {
+			details := make(map[string]interface{}, len(dirs))
+			var errs []error
+			for _, dir := range dirs {
+				free, err := freeSpace(dir)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+					continue
+				}
+				details[dir] = map[string]interface{}{"free_bytes": free}
+				if free < minFree {
+					errs = append(errs, fmt.Errorf("%s: %d bytes free, want at least %d", dir, free, minFree))
+				}
+			}
+			return details, errors.Join(errs...)
+		},
+	}
+}
@@ -0,0 +1,195 @@
+// Package health 执行就绪检查: 数据库连接池、Redis、数据库迁移版本、日志目录的磁盘空间等.
+// 每项检查有单独的超时, 结果缓存一段时间, 探针频繁请求时不会压垮依赖的服务
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 检查结果的状态
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check 一项就绪检查, Run 返回的 details 会原样输出, 不应包含密码等敏感信息
+type Check struct {
+	Name string
+	// Timeout 为 0 时使用 Options.Timeout
+	Timeout time.Duration
+	Run     func(ctx context.Context) (details map[string]interface{}, err error)
+}
+
+// Options 检查配置
+type Options struct {
+	// Timeout 每项检查的默认超时
+	Timeout time.Duration
+	// CacheTTL 检查结果的缓存时间, 为 0 时每次请求都执行检查
+	CacheTTL time.Duration
+}
+
+// Result 一项检查的结果
+type Result struct {
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	DurationMS float64                `json:"duration_ms"`
+	CheckedAt  time.Time              `json:"checked_at"`
+	Cached     bool                   `json:"cached"`
+}
+
+// Report 所有检查的结果, 任何一项失败或服务正在退出时 Status 为 fail
+type Report struct {
+	Status   string            `json:"status"`
+	Draining bool              `json:"draining,omitempty"`
+	Checks   map[string]Result `json:"checks"`
+}
+
+// entry 一项检查和它的缓存结果, mu 保证同一项检查同时只执行一次
+type entry struct {
+	check Check
+
+	mu     sync.Mutex
+	result Result
+}
+
+var (
+	mu      sync.RWMutex
+	options = Options{Timeout: 2 * time.Second, CacheTTL: 5 * time.Second}
+	entries []*entry
+
+	// draining 服务正在退出, 就绪检查直接失败, 负载均衡不再转发新请求
+	draining atomic.Bool
+)
+
+// Configure 设置超时和缓存时间
+func Configure(opts Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	options = opts
+}
+
+// Register 添加一项就绪检查, 同名的检查会被替换
+func Register(check Check) error {
+	if check.Name == "" || check.Run == nil {
+		return errors.New("health check requires a name and a run function")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, e := range entries {
+		if e.check.Name == check.Name {
+			entries[i] = &entry{check: check}
+			return nil
+		}
+	}
+	entries = append(entries, &entry{check: check})
+	return nil
+}
+
+// Reset 删除所有检查并清除退出标记, 用于测试
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	entries = nil
+	draining.Store(false)
+}
+
+// SetDraining 标记服务正在退出, 之后 Run 不再执行检查, 直接返回失败
+func SetDraining() {
+	draining.Store(true)
+}
+
+// Run 并发执行所有检查, 缓存时间内的检查直接返回上次的结果
+func Run(ctx context.Context) Report {
+	if draining.Load() {
+		return Report{Status: StatusFail, Draining: true, Checks: map[string]Result{}}
+	}
+
+	mu.RLock()
+	opts := options
+	list := append([]*entry(nil), entries...)
+	mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(list))}
+	results := make([]Result, len(list))
+	var wg sync.WaitGroup
+	for i, e := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = e.run(ctx, opts)
+		}()
+	}
+	wg.Wait()
+
+	for i, e := range list {
+		report.Checks[e.check.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// Names 返回已注册的检查名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.check.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *entry) run(ctx context.Context, opts Options) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.result.CheckedAt.IsZero() && time.Since(e.result.CheckedAt) < opts.CacheTTL {
+		cached := e.result
+		cached.Cached = true
+		return cached
+	}
+
+	timeout := e.check.Timeout
+	if timeout <= 0 {
+		timeout = opts.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := e.safeRun(ctx)
+	result := Result{
+		Status:     StatusOK,
+		Details:    details,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt:  start,
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	e.result = result
+	return result
+}
+
+// safeRun 检查 panic 时记为失败, 不影响其它检查
+func (e *entry) safeRun(ctx context.Context) (details map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return e.check.Run(ctx)
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"openapphub/internal/model"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+	Configure(Options{Timeout: 50 * time.Millisecond, CacheTTL: time.Minute})
+
+	var runs atomic.Int32
+	_ = Register(Check{Name: "ok", Run: func(ctx context.Context) (map[string]interface{}, error) {
+		runs.Add(1)
+		return map[string]interface{}{"n": 1}, nil
+	}})
+	_ = Register(Check{Name: "slow", Run: func(ctx context.Context) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}})
+	_ = Register(Check{Name: "panic", Run: func(ctx context.Context) (map[string]interface{}, error) {
+		panic("boom")
+	}})
+
+	report := Run(context.Background())
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want fail", report.Status)
+	}
+	if r := report.Checks["ok"]; r.Status != StatusOK || r.Cached {
+		t.Errorf("ok = %+v", r)
+	}
+	if r := report.Checks["slow"]; r.Status != StatusFail || !strings.Contains(r.Error, "deadline") {
+		t.Errorf("slow = %+v, want timeout", r)
+	}
+	if r := report.Checks["panic"]; r.Status != StatusFail || !strings.Contains(r.Error, "boom") {
+		t.Errorf("panic = %+v", r)
+	}
+
+	// 缓存时间内不再执行检查
+	report = Run(context.Background())
+	if !report.Checks["ok"].Cached || runs.Load() != 1 {
+		t.Errorf("check ran %d times, want cached result", runs.Load())
+	}
+
+	// 退出时直接失败, 不再执行检查
+	SetDraining()
+	report = Run(context.Background())
+	if report.Status != StatusFail || !report.Draining || len(report.Checks) != 0 {
+		t.Errorf("draining report = %+v", report)
+	}
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := freeSpace(dir); err != nil {
+		t.Skip(err)
+	}
+	if _, err := Disk([]string{dir}, 0).Run(context.Background()); err != nil {
+		t.Errorf("disk check failed: %v", err)
+	}
+	if _, err := Disk([]string{dir}, 1<<62).Run(context.Background()); err == nil {
+		t.Error("expected error when free space is below the minimum")
+	}
+}
+
+// 添加迁移时需要同步修改 model.SchemaVersion
+func TestSchemaVersion(t *testing.T) {
+	files, err := filepath.Glob("../../database/migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	var latest int
+	for _, file := range files {
+		version, err := strconv.Atoi(strings.SplitN(filepath.Base(file), "_", 2)[0])
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		latest = max(latest, version)
+	}
+	if latest != model.SchemaVersion {
+		t.Errorf("model.SchemaVersion = %d, latest migration is %d", model.SchemaVersion, latest)
+	}
+}
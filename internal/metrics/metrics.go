@@ -0,0 +1,126 @@
+// Package metrics 定义服务导出的 Prometheus 指标, 由 /metrics 接口输出.
+// 指标的标签只使用路由模板、策略名等有限的值, 避免标签基数随请求增长
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace 所有指标名称的前缀
+const Namespace = "openapphub"
+
+// 缓存请求的结果, 见 CacheRequests
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheShared = "shared" // 并发请求共享同一次处理器执行的结果
+	CacheBypass = "bypass"
+)
+
+// 登录结果, 见 LoginAttempts
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure" // 参数错误或用户名密码错误
+	LoginError   = "error"   // 服务器错误
+)
+
+// Registry 服务的指标, 不使用 prometheus.DefaultRegisterer, 以免依赖库注册的指标混入
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests 按路由模板、方法和状态码统计的请求数, 未匹配路由的请求 route 为 unmatched
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration 请求处理时间
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	// HTTPInFlight 正在处理的请求数
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	// CacheRequests 响应缓存的命中、未命中、共享和跳过次数
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Response cache lookups by route template and result (hit, miss, shared, bypass).",
+	}, []string{"route", "result"})
+
+	// RateLimitRejections 被限流拒绝的请求, reason 为 exceeded(超出限额) 或 unavailable(限流存储不可用)
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "rate_limit",
+		Name:      "rejections_total",
+		Help:      "Requests rejected by rate limiting, by policy and reason (exceeded, unavailable).",
+	}, []string{"policy", "reason"})
+
+	// DBQueryDuration GORM 执行 SQL 的时间, operation 为 create、query、update、delete、row 或 raw
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latency by operation, table and status (ok, error).",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "status"})
+
+	// LoginAttempts 登录次数
+	LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "auth",
+		Name:      "login_attempts_total",
+		Help:      "Login attempts by result (success, failure, error).",
+	}, []string{"result"})
+
+	// AuditEvents 审计事件的写入结果, status 为 written、failed(数据库写入失败) 或 dropped(队列已满)
+	AuditEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "audit",
+		Name:      "events_total",
+		Help:      "Audit events by write status (written, failed, dropped).",
+	}, []string{"status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		CacheRequests,
+		RateLimitRejections,
+		DBQueryDuration,
+		LoginAttempts,
+		AuditEvents,
+	)
+}
+
+// Handler 输出所有指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB 导出数据库连接池的统计, 见 sql.DBStats
+func RegisterDB(name string, db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPoolCollector 导出 Redis 连接池的统计, 见 redis.PoolStats
+type redisPoolCollector struct {
+	stats func() *redis.PoolStats
+
+	hits, misses, timeouts *prometheus.Desc
+	total, idle, stale     *prometheus.Desc
+}
+
+// RegisterRedis 导出 Redis 连接池的统计, name 用于区分多个连接池
+func RegisterRedis(name string, stats func() *redis.PoolStats) error {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(Namespace, "redis_pool", metric), help, nil, labels)
+	}
+	return Registry.Register(&redisPoolCollector{
+		stats:    stats,
+		hits:     desc("hits_total", "Times a free connection was found in the pool."),
+		misses:   desc("misses_total", "Times a free connection was not found in the pool."),
+		timeouts: desc("timeouts_total", "Times a wait for a connection timed out."),
+		total:    desc("connections", "Connections in the pool."),
+		idle:     desc("idle_connections", "Idle connections in the pool."),
+		stale:    desc("stale_connections_total", "Stale connections removed from the pool."),
+	})
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.hits, c.misses, c.timeouts, c.total, c.idle, c.stale} {
+		ch <- d
+	}
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	if s == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(s.StaleConns))
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminTokenHeader 管理接口令牌的请求头
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminAuth 校验管理接口令牌, 令牌为空时拒绝所有请求, 避免未配置令牌时接口对外开放
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given := c.GetHeader(AdminTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			RequestLogger(c).Warn("Admin request rejected",
+				zap.String("path", c.Request.URL.Path),
+				zap.Bool("token_configured", token != ""),
+				zap.Bool("token_present", given != ""))
+			c.AbortWithStatusJSON(http.StatusForbidden, serializer.Response{
+				Code: serializer.CodeNoRightErr,
+				Msg:  i18n.T(c, "Common.AccessDenied"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
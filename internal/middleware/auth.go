@@ -3,8 +3,8 @@ package middleware
 import (
 	"openapphub/internal/auth"
 	"openapphub/internal/model"
+	"openapphub/pkg/i18n"
 	"openapphub/pkg/serializer"
-	"os"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -14,15 +14,15 @@ import (
 // CurrentUser 获取登录用户
 func CurrentUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authMode := os.Getenv("AUTH_MODE")
-		if authMode == "jwt" {
+		authMode := auth.Mode()
+		if authMode == auth.ModeJWT {
 			tokenString := c.GetHeader("Authorization")
 			if tokenString != "" {
-				claims, err := auth.ParseToken(tokenString)
+				claims, err := auth.ParseToken(c, tokenString)
 				if err == nil {
-					user, err := model.GetUser(claims.UserID)
+					user, err := model.GetUser(c, claims.UserID)
 					if err == nil {
-						c.Set("user", &user)
+						setCurrentUser(c, &user)
 					}
 				}
 			}
@@ -30,9 +30,9 @@ func CurrentUser() gin.HandlerFunc {
 			session := sessions.Default(c)
 			uid := session.Get("user_id")
 			if uid != nil {
-				user, err := model.GetUser(uid)
+				user, err := model.GetUser(c, uid)
 				if err == nil {
-					c.Set("user", &user)
+					setCurrentUser(c, &user)
 				}
 			}
 		}
@@ -40,21 +40,29 @@ func CurrentUser() gin.HandlerFunc {
 	}
 }
 
+// setCurrentUser 设置登录用户, 用户设置了语言偏好时按偏好返回消息
+func setCurrentUser(c *gin.Context, user *model.User) {
+	c.Set("user", user)
+	if user.Locale != "" {
+		setLocale(c, i18n.Negotiate(user.Locale, c.GetHeader("Accept-Language")))
+	}
+}
+
 // AuthRequired 需要登录
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authMode := os.Getenv("AUTH_MODE")
+		authMode := auth.Mode()
 		var user *model.User
 
-		GetZapLogger().Info("authMode", zap.String("authMode", authMode))
-		if authMode == "jwt" {
+		RequestLogger(c).Info("authMode", zap.String("authMode", authMode))
+		if authMode == auth.ModeJWT {
 			user = authenticateJWT(c)
 		} else {
 			user = authenticateSession(c)
 		}
 
 		if user == nil {
-			c.JSON(401, serializer.CheckLogin())
+			c.JSON(401, serializer.CheckLogin(c))
 			c.Abort()
 			return
 		}
@@ -70,12 +78,12 @@ func authenticateJWT(c *gin.Context) *model.User {
 		return nil
 	}
 
-	claims, err := auth.ParseToken(tokenString)
+	claims, err := auth.ParseToken(c, tokenString)
 	if err != nil {
 		return nil
 	}
 
-	user, err := model.GetUser(claims.UserID)
+	user, err := model.GetUser(c, claims.UserID)
 	if err != nil {
 		return nil
 	}
@@ -90,7 +98,7 @@ func authenticateSession(c *gin.Context) *model.User {
 		return nil
 	}
 
-	user, err := model.GetUser(userID)
+	user, err := model.GetUser(c, userID)
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,300 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/pkg/cache"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheHitsPrefix 命中计数 key 的前缀, 与缓存条目保存在同一个后端
+const cacheHitsPrefix = "hits:"
+
+// cacheTagsKey 处理器通过 AddCacheTags 写入的标签在 gin.Context 中的 key
+const cacheTagsKey = "cache_tags"
+
+func cacheHitsKey(key string) string {
+	return cacheHitsPrefix + key
+}
+
+// CacheRouteStats 单个路由的缓存统计, 只统计当前实例.
+// Stores 只统计写入成功的条目; Invalidations 只统计通过管理接口删除的条目, 不包括 TTL 过期和 Redis 的内存淘汰
+type CacheRouteStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Shared        int64 `json:"shared"`
+	Bypasses      int64 `json:"bypasses"`
+	Stores        int64 `json:"stores"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+var cacheStats = struct {
+	sync.Mutex
+	routes map[string]*CacheRouteStats
+}{routes: make(map[string]*CacheRouteStats)}
+
+func recordCacheStats(route string, update func(s *CacheRouteStats)) {
+	cacheStats.Lock()
+	defer cacheStats.Unlock()
+
+	stats, ok := cacheStats.routes[route]
+	if !ok {
+		stats = &CacheRouteStats{}
+		cacheStats.routes[route] = stats
+	}
+	update(stats)
+}
+
+// CacheStats 返回按路由汇总的缓存统计快照
+func CacheStats() map[string]CacheRouteStats {
+	cacheStats.Lock()
+	defer cacheStats.Unlock()
+
+	snapshot := make(map[string]CacheRouteStats, len(cacheStats.routes))
+	for route, stats := range cacheStats.routes {
+		snapshot[route] = *stats
+	}
+	return snapshot
+}
+
+// cacheRouteName 统计使用的路由名称, 例如 "GET /api/v1/ping"
+func cacheRouteName(c *gin.Context) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return c.Request.Method + " " + route
+}
+
+func cachedRouteName(response *CachedResponse) string {
+	route := response.Route
+	if route == "" {
+		route = response.Path
+	}
+	return response.Method + " " + route
+}
+
+// AddCacheTags 为当前请求的缓存条目添加标签, 需要在 CacheMiddleware 之后的处理器中调用
+func AddCacheTags(c *gin.Context, tags ...string) {
+	existing := c.GetStringSlice(cacheTagsKey)
+	c.Set(cacheTagsKey, append(existing, tags...))
+}
+
+func cacheTags(c *gin.Context) []string {
+	tags := []string{"route:" + c.FullPath()}
+	return append(tags, c.GetStringSlice(cacheTagsKey)...)
+}
+
+// ErrCacheKeyOutOfScope 缓存管理接口只能操作当前版本的响应缓存, 不能触及同一后端中的其它数据
+var ErrCacheKeyOutOfScope = errors.New("key is outside the response cache")
+
+// responseCachePrefix 响应缓存 key 的公共前缀, 见 generateCacheKeyInternal
+func responseCachePrefix() string {
+	return cacheKeyVersion + ":"
+}
+
+// checkCacheKeyScope 检查 key 或前缀属于响应缓存
+func checkCacheKeyScope(key string) error {
+	if !strings.HasPrefix(key, responseCachePrefix()) {
+		return fmt.Errorf("%w: %q does not start with %q", ErrCacheKeyOutOfScope, key, responseCachePrefix())
+	}
+	return nil
+}
+
+// evictCacheEntry 删除缓存条目及其命中计数, 并记录对应路由的删除次数
+func evictCacheEntry(ctx context.Context, store cache.Store, key string) error {
+	if err := checkCacheKeyScope(key); err != nil {
+		return err
+	}
+	// 命中计数随条目一起删除
+	if strings.HasPrefix(key, cacheHitsPrefix) {
+		return cache.ErrNotFound
+	}
+
+	response, getErr := getCachedResponse(ctx, store, key)
+	if err := store.Del(ctx, key); err != nil {
+		return err
+	}
+	store.Del(ctx, cacheHitsKey(key))
+
+	route := "unknown"
+	if getErr == nil {
+		route = cachedRouteName(response)
+	}
+	recordCacheStats(route, func(s *CacheRouteStats) { s.Invalidations++ })
+	return nil
+}
+
+// CacheEntryInfo 缓存条目的详细信息
+type CacheEntryInfo struct {
+	Key       string    `json:"key"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Size      int       `json:"size"`
+	TTL       int64     `json:"ttl"` // 剩余秒数, -1 表示永不过期
+	Age       int64     `json:"age"` // 已缓存秒数
+	Tags      []string  `json:"tags"`
+	Hits      int64     `json:"hits"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InspectCacheEntry 查看缓存条目的路由、大小、过期时间、命中次数等信息
+func InspectCacheEntry(ctx context.Context, store cache.Store, key string) (*CacheEntryInfo, error) {
+	if err := checkCacheKeyScope(key); err != nil {
+		return nil, err
+	}
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	response, err := getCachedResponse(ctx, store, key)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := store.TTL(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CacheEntryInfo{
+		Key:       key,
+		Method:    response.Method,
+		Route:     response.Route,
+		Path:      response.Path,
+		Status:    response.Status,
+		Size:      len(data),
+		TTL:       int64(ttl / time.Second),
+		Tags:      response.Tags,
+		CreatedAt: response.CreatedAt,
+	}
+	if ttl == cache.NoExpiration {
+		info.TTL = -1
+	}
+	if !response.CreatedAt.IsZero() {
+		info.Age = int64(time.Since(response.CreatedAt) / time.Second)
+	}
+	if hits, err := store.Get(ctx, cacheHitsKey(key)); err == nil {
+		info.Hits, _ = strconv.ParseInt(hits, 10, 64)
+	}
+	return info, nil
+}
+
+// CacheEntrySummary 缓存列表中的条目
+type CacheEntrySummary struct {
+	Key string `json:"key"`
+	TTL int64  `json:"ttl"`
+}
+
+// ListCacheEntries 按前缀分页列出缓存条目, 返回下一页的游标, 游标为空表示没有更多数据
+func ListCacheEntries(ctx context.Context, store cache.Store, prefix, cursor string, limit int64) ([]CacheEntrySummary, string, error) {
+	if prefix == "" {
+		prefix = responseCachePrefix()
+	}
+	if err := checkCacheKeyScope(prefix); err != nil {
+		return nil, "", err
+	}
+	keys, next, err := store.Scan(ctx, prefix, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := make([]CacheEntrySummary, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, cacheHitsPrefix) {
+			continue
+		}
+		ttl, err := store.TTL(ctx, key)
+		if errors.Is(err, cache.ErrNotFound) {
+			continue // 扫描期间过期
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		entry := CacheEntrySummary{Key: key, TTL: int64(ttl / time.Second)}
+		if ttl == cache.NoExpiration {
+			entry.TTL = -1
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, next, nil
+}
+
+// cacheWarmKey 标记内部预热请求的 context key, 外部请求无法伪造
+type cacheWarmKey struct{}
+
+// warmState 记录单个预热请求是否写入了缓存
+type warmState struct {
+	cached bool
+}
+
+func cacheWarmState(c *gin.Context) *warmState {
+	state, _ := c.Request.Context().Value(cacheWarmKey{}).(*warmState)
+	return state
+}
+
+// warmCacheEntry 执行处理器并同步写入缓存
+func warmCacheEntry(c *gin.Context, store cache.Store, key string, policy *CachePolicy, state *warmState) {
+	response, shareable := recordResponse(c)
+	if !shareable || !policy.storable(response) {
+		return
+	}
+	if err := cacheResponse(c, store, key, response, policy.TTL); err != nil {
+		return
+	}
+	recordCacheStats(cacheRouteName(c), func(s *CacheRouteStats) { s.Stores++ })
+	state.cached = true
+}
+
+// WarmRoute 需要预热的路由
+type WarmRoute struct {
+	Method string `json:"method" binding:"required,oneof=GET POST"`
+	Path   string `json:"path" binding:"required"` // 可以带查询参数
+	Body   string `json:"body"`
+}
+
+// WarmResult 单个路由的预热结果
+type WarmResult struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Cached bool   `json:"cached"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WarmCache 在进程内依次重放路由, 由 CacheMiddleware 执行处理器并写入缓存
+func WarmCache(ctx context.Context, handler http.Handler, routes []WarmRoute) []WarmResult {
+	results := make([]WarmResult, 0, len(routes))
+	for _, route := range routes {
+		result := WarmResult{Method: route.Method, Path: route.Path}
+		state := &warmState{}
+
+		req, err := http.NewRequestWithContext(context.WithValue(ctx, cacheWarmKey{}, state), route.Method, route.Path, strings.NewReader(route.Body))
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if route.Body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		result.Status = w.Code
+		result.Cached = state.cached
+		results = append(results, result)
+	}
+	return results
+}
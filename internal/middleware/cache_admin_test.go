@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/pkg/cache"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWarmCacheAndInspect(t *testing.T) {
+	store := cache.NewMemoryStore("test:")
+	ctx := context.Background()
+
+	r := gin.New()
+	r.GET("/items/:id", CacheMiddlewareWithStore(store, time.Minute), func(c *gin.Context) {
+		AddCacheTags(c, "item:"+c.Param("id"))
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+
+	results := WarmCache(ctx, r, []WarmRoute{
+		{Method: http.MethodGet, Path: "/items/1"},
+		{Method: http.MethodGet, Path: "/missing"},
+	})
+	if !results[0].Cached || results[0].Status != http.StatusOK {
+		t.Fatalf("warm /items/1 = %+v, want cached 200", results[0])
+	}
+	if results[1].Cached || results[1].Status != http.StatusNotFound {
+		t.Fatalf("warm /missing = %+v, want uncached 404", results[1])
+	}
+
+	entries, next, err := ListCacheEntries(ctx, store, "v1:/items", "", 10)
+	if err != nil || next != "" || len(entries) != 1 {
+		t.Fatalf("ListCacheEntries = %v, %q, %v", entries, next, err)
+	}
+
+	info, err := InspectCacheEntry(ctx, store, entries[0].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Route != "/items/:id" || info.Path != "/items/1" || info.Status != http.StatusOK {
+		t.Errorf("unexpected entry info: %+v", info)
+	}
+	if len(info.Tags) != 2 || info.Tags[1] != "item:1" {
+		t.Errorf("tags = %v", info.Tags)
+	}
+	if info.TTL <= 0 || info.TTL > 60 {
+		t.Errorf("ttl = %d", info.TTL)
+	}
+
+	// 命中后计数增加, 统计按路由模板汇总
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil))
+	info, _ = InspectCacheEntry(ctx, store, entries[0].Key)
+	if info.Hits != 1 {
+		t.Errorf("hits = %d, want 1", info.Hits)
+	}
+	if stats := CacheStats()["GET /items/:id"]; stats.Hits < 1 || stats.Stores < 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// 管理接口删除的条目计入 Invalidations
+	if err := evictCacheEntry(ctx, store, entries[0].Key); err != nil {
+		t.Fatal(err)
+	}
+	if stats := CacheStats()["GET /items/:id"]; stats.Invalidations < 1 {
+		t.Errorf("stats after invalidation = %+v", stats)
+	}
+}
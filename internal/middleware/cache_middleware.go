@@ -2,18 +2,26 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
-	"encoding/gob"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
+	"net/http"
+	"openapphub/internal/metrics"
+	"openapphub/internal/tracing"
 	"openapphub/internal/util"
 	"openapphub/pkg/cache"
+	"openapphub/pkg/redact"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -81,131 +89,253 @@ func normalizeJSONValue(v interface{}) interface{} {
 	}
 }
 
+// CachedResponse 缓存的响应, 保存状态码、完整的多值响应头和响应体
 type CachedResponse struct {
 	Status int
-	Header gin.H
+	Header http.Header
 	Data   []byte
+
+	// 以下为管理接口使用的元数据
+	Method    string
+	Route     string // 路由模板, 例如 /api/v1/user/:id
+	Path      string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// cacheResult 是 singleflight 中 leader 返回给所有等待者的结果
+type cacheResult struct {
+	response  *CachedResponse
+	fromCache bool
+	// shareable 为 false 时响应带有 Set-Cookie 等请求私有的内容, 等待者需要自己执行处理器
+	shareable bool
+}
+
+// uncachedHeaders 不会被缓存和重放的响应头, 限流响应头由路由组中间件按请求设置, 请求 ID 由 RequestID 中间件设置
+var uncachedHeaders = map[string]struct{}{
+	"Content-Length":        {},
+	"Date":                  {},
+	"Set-Cookie":            {},
+	"X-From-Cache":          {},
+	"Ratelimit":             {},
+	"Ratelimit-Policy":      {},
+	"Retry-After":           {},
+	"X-Ratelimit-Limit":     {},
+	"X-Ratelimit-Remaining": {},
+	"X-Ratelimit-Reset":     {},
+	"X-Request-Id":          {},
 }
 
 var (
 	group singleflight.Group
+
+	// cacheEncoder 缓存条目的编码方式
+	cacheEncoder = cache.EntryEncoder{Codec: cache.CodecGob}
+	// cacheKeyVersion 缓存 key 的版本前缀, 修改后所有旧条目不再被读取, 由 TTL 自然淘汰
+	cacheKeyVersion = "v1"
 )
 
+// ConfigureCacheEncoding 设置缓存条目编码和 key 版本, 需要在创建路由之前调用
+func ConfigureCacheEncoding(encoder cache.EntryEncoder, keyVersion string) error {
+	if err := encoder.Validate(); err != nil {
+		return err
+	}
+	cacheEncoder = encoder
+	if keyVersion != "" {
+		cacheKeyVersion = keyVersion
+	}
+	return nil
+}
+
+// CacheMiddleware 使用全局缓存后端缓存响应
 func CacheMiddleware(duration time.Duration) gin.HandlerFunc {
+	return CacheMiddlewareWithStore(cache.Default(), duration)
+}
+
+// CacheMiddlewareWithStore 使用指定的缓存后端缓存响应
+func CacheMiddlewareWithStore(store cache.Store, duration time.Duration) gin.HandlerFunc {
+	policy := defaultCachePolicy(duration)
 	return func(c *gin.Context) {
-		// Allow caching for GET and POST requests
-		if c.Request.Method != "GET" && c.Request.Method != "POST" {
-			c.Next()
-			return
-		}
+		serveCached(c, store, policy)
+	}
+}
 
-		// Check if we should bypass the cache
-		if c.GetHeader("X-Bypass-Cache") == "true" {
-			c.Next()
-			return
-		}
+// serveCached 按策略从缓存返回响应, 未命中时执行处理器并写入缓存
+func serveCached(c *gin.Context, store cache.Store, policy *CachePolicy) {
+	if !slices.Contains(policy.Methods, c.Request.Method) {
+		c.Next()
+		return
+	}
 
-		// Generate cache key
-		key := GenerateCacheKey(c)
+	route := cacheRouteName(c)
 
-		var cachedResponse *CachedResponse
-		var fromCache bool
+	// Check if we should bypass the cache
+	if policy.bypassed(c) {
+		recordCacheStats(route, func(s *CacheRouteStats) { s.Bypasses++ })
+		metrics.CacheRequests.WithLabelValues(route, metrics.CacheBypass).Inc()
+		c.Next()
+		return
+	}
 
-		// Use singleflight to handle concurrent requests
-		resp, err, _ := group.Do(key, func() (interface{}, error) {
-			// Try to get the cached response
-			cr, err := getCachedResponse(c, key)
-			if err == nil {
-				fromCache = true
-				return cr, nil
-			}
+	// Generate cache key
+	key := policy.cacheKey(c)
 
-			// If not in cache, create a custom ResponseWriter
-			w := &responseWriter{
-				ResponseWriter: c.Writer,
-				body:           &bytes.Buffer{},
-			}
-			c.Writer = w
+	// 预热请求跳过查找, 直接执行处理器并同步写入缓存
+	if state := cacheWarmState(c); state != nil {
+		warmCacheEntry(c, store, key, policy, state)
+		return
+	}
 
-			// Process the request
-			c.Next()
+	// leader 只会在真正执行处理器的请求中被置为 true
+	leader := false
+
+	// Use singleflight to handle concurrent requests
+	resp, err, _ := group.Do(key, func() (interface{}, error) {
+		// Try to get the cached response, 查找记录为单独的 span, 未命中不算错误
+		ctx, span := tracing.Start(c, "cache.lookup", trace.WithAttributes(attribute.String("cache.route", route)))
+		cr, err := getCachedResponse(ctx, store, key)
+		span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+		span.End()
+		if err == nil {
+			recordCacheStats(route, func(s *CacheRouteStats) { s.Hits++ })
+			metrics.CacheRequests.WithLabelValues(route, metrics.CacheHit).Inc()
+			// 命中计数已过期时重新创建, 过期时间不超过缓存条目
+			store.Incr(c, cacheHitsKey(key), policy.TTL)
+			return &cacheResult{response: cr, fromCache: true, shareable: true}, nil
+		}
 
-			// Create the response
-			response := &CachedResponse{
-				Status: w.Status(),
-				Header: make(gin.H),
-				Data:   w.body.Bytes(),
-			}
+		leader = true
+		recordCacheStats(route, func(s *CacheRouteStats) { s.Misses++ })
+		metrics.CacheRequests.WithLabelValues(route, metrics.CacheMiss).Inc()
+		response, shareable := recordResponse(c)
+
+		// Cache the response if it's successful
+		if shareable && policy.storable(response) {
+			// 请求结束后 context 会被取消, 缓存写入不能跟随请求的生命周期; 退出前会等待写入完成
+			ctx := context.WithoutCancel(c.Request.Context())
+			util.Go("cache.store", func() {
+				if err := cacheResponse(ctx, store, key, response, policy.TTL); err != nil {
+					util.LogCtx(ctx).Warning("缓存写入失败: %s: %v", route, err)
+					return
+				}
+				recordCacheStats(route, func(s *CacheRouteStats) { s.Stores++ })
+			})
+		}
 
-			// Copy headers
-			for k, v := range w.Header() {
-				response.Header[k] = v[0]
-			}
+		return &cacheResult{response: response, shareable: shareable}, nil
+	})
 
-			// Cache the response if it's successful
-			if w.Status() >= 200 && w.Status() < 300 {
-				go cacheResponse(c.Copy(), key, response, duration) // Cache asynchronously
-			}
+	// The leader has already written its own response through c.Next()
+	if leader {
+		return
+	}
 
-			return response, nil
-		})
+	result, ok := resp.(*cacheResult)
+	if err != nil || !ok || !result.shareable {
+		c.Next() // Fall back to running the handlers for this request
+		return
+	}
 
-		if err != nil {
-			c.Next() // If there's an error, just continue without caching
-			return
-		}
+	if result.fromCache {
+		c.Header("X-From-Cache", "true")
+	} else {
+		recordCacheStats(route, func(s *CacheRouteStats) { s.Shared++ })
+		metrics.CacheRequests.WithLabelValues(route, metrics.CacheShared).Inc()
+		c.Header("X-From-Cache", "shared")
+	}
+	replayResponse(c, result.response)
+	c.Abort() // Prevent further handlers from being called
+}
 
-		cachedResponse, ok := resp.(*CachedResponse)
-		if !ok || cachedResponse == nil {
-			// This should not happen, but if it does, we've already written the response
-			return
-		}
+// recordResponse 执行后续处理器并记录响应,
+// 只保留处理器新增或修改的响应头, 上游中间件写入的请求相关头部(CORS、限流等)不会被记录
+func recordResponse(c *gin.Context) (*CachedResponse, bool) {
+	before := c.Writer.Header().Clone()
 
-		// If the response is from cache, write it to the client
-		if fromCache {
-			// Set headers and write response
-			for k, v := range cachedResponse.Header {
-				c.Header(k, fmt.Sprint(v))
-			}
-			c.Header("X-From-Cache", "true")
-			c.Data(cachedResponse.Status, c.Writer.Header().Get("Content-Type"), cachedResponse.Data)
+	w := &responseWriter{
+		ResponseWriter: c.Writer,
+		body:           &bytes.Buffer{},
+	}
+	c.Writer = w
+	defer func() { c.Writer = w.ResponseWriter }()
+
+	// Process the request
+	c.Next()
+
+	response := &CachedResponse{
+		Status:    w.Status(),
+		Header:    make(http.Header),
+		Data:      w.body.Bytes(),
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      c.Request.URL.Path,
+		Tags:      cacheTags(c),
+		CreatedAt: time.Now(),
+	}
+	shareable := true
+	for k, v := range w.Header() {
+		if prev, ok := before[k]; ok && slices.Equal(prev, v) {
+			continue
+		}
+		if k == "Set-Cookie" {
+			shareable = false
+		}
+		if _, skip := uncachedHeaders[k]; skip {
+			continue
 		}
-		// If it's not from cache, the response has already been written by c.Next()
-		c.Abort() // Prevent further handlers from being called
+		response.Header[k] = slices.Clone(v)
 	}
+	return response, shareable
 }
 
-func getCachedResponse(c *gin.Context, key string) (*CachedResponse, error) {
+// replayResponse 将缓存的响应写回客户端
+func replayResponse(c *gin.Context, response *CachedResponse) {
+	header := c.Writer.Header()
+	for k, v := range response.Header {
+		header[k] = slices.Clone(v)
+	}
+	c.Data(response.Status, response.Header.Get("Content-Type"), response.Data)
+}
+
+func getCachedResponse(ctx context.Context, store cache.Store, key string) (*CachedResponse, error) {
 	var response CachedResponse
-	data, err := cache.Get(c, key)
+	data, err := store.Get(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
-	decoder := gob.NewDecoder(bytes.NewReader([]byte(data)))
-	err = decoder.Decode(&response)
-	if err != nil {
+	// 无法解析的旧版本或损坏条目按未命中处理, 会被新的响应覆盖
+	if err := cacheEncoder.Decode([]byte(data), &response); err != nil {
 		return nil, err
 	}
 
 	return &response, nil
 }
 
-func cacheResponse(c *gin.Context, key string, response *CachedResponse, duration time.Duration) error {
-	var buf bytes.Buffer
-	encoder := gob.NewEncoder(&buf)
-	err := encoder.Encode(response)
+func encodeCachedResponse(response *CachedResponse) (string, error) {
+	data, err := cacheEncoder.Encode(response)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func cacheResponse(ctx context.Context, store cache.Store, key string, response *CachedResponse, duration time.Duration) error {
+	data, err := encodeCachedResponse(response)
 	if err != nil {
 		return err
 	}
 
-	return cache.Set(c, key, buf.String(), duration)
+	if err := store.Set(ctx, key, data, duration); err != nil {
+		return err
+	}
+	// 命中计数与缓存条目同时过期
+	return store.Set(ctx, cacheHitsKey(key), 0, duration)
 }
 
 type responseWriter struct {
 	gin.ResponseWriter
-	body   *bytes.Buffer
-	status int
+	body *bytes.Buffer
 }
 
 func (w *responseWriter) Write(b []byte) (int, error) {
@@ -218,59 +348,95 @@ func (w *responseWriter) WriteString(s string) (int, error) {
 	return w.ResponseWriter.WriteString(s)
 }
 
-func (w *responseWriter) WriteHeader(status int) {
-	w.status = status
-	w.ResponseWriter.WriteHeader(status)
-}
-
-func (w *responseWriter) Status() int {
-	return w.status
-}
-
 func (w *responseWriter) Body() []byte {
 	return w.body.Bytes()
 }
 
 func InvalidateCache(c *gin.Context, key string) error {
-	util.Log().Info("InvalidateCache: %s", key)
-	return cache.Del(c, key)
+	util.LogCtx(c).Info("InvalidateCache: %s", RedactCacheKey(key))
+	return evictCacheEntry(c, cache.Default(), key)
 }
 
 func RefreshCache(c *gin.Context, key string, duration time.Duration) error {
 	// Get the current cached response
-	cachedResponse, err := getCachedResponse(c, key)
+	store := cache.Default()
+	cachedResponse, err := getCachedResponse(c, store, key)
 	if err != nil {
 		return err
 	}
 
-	// Re-cache the response with a new duration
-	return cacheResponse(c, key, cachedResponse, duration)
+	// Re-cache the response with a new duration, keeping the hit count
+	data, err := encodeCachedResponse(cachedResponse)
+	if err != nil {
+		return err
+	}
+	if err := store.Set(c, key, data, duration); err != nil {
+		return err
+	}
+	if err := store.Expire(c, cacheHitsKey(key), duration); err != nil && !errors.Is(err, cache.ErrNotFound) {
+		return err
+	}
+	return nil
 }
 
+// ClearCacheByPrefix 删除指定前缀下的所有缓存条目, 并按路由记录删除次数.
+// 前缀需要以 key 版本开头, 例如 v1:/api/v1/items, 不会删除缓存以外的数据
 func ClearCacheByPrefix(c *gin.Context, prefix string) error {
-	return cache.DelByPrefix(c, prefix)
+	if err := checkCacheKeyScope(prefix); err != nil {
+		return err
+	}
+	store := cache.Default()
+	deleted := 0
+	cursor := ""
+	for {
+		keys, next, err := store.Scan(c, prefix, cursor, 100)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := evictCacheEntry(c, store, key); err == nil {
+				deleted++
+			} else if !errors.Is(err, cache.ErrNotFound) {
+				return err
+			}
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if deleted == 0 {
+		return cache.ErrNoKeysMatched
+	}
+	return nil
 }
 
-func GenerateCacheKey(c *gin.Context) string {
-	method := c.Request.Method
-	path := c.Request.URL.Path
-	query := c.Request.URL.RawQuery
-	body := getRequestBody(c)
-
-	key := generateCacheKeyInternal(method, path, query, body)
-	util.Log().Info(fmt.Sprintf("Generated cache key for request - Method: %s, Path: %s, Key: %s", method, path, key))
+// RedactCacheKey 隐藏缓存 key 中查询参数的敏感值, 记录日志和审计时使用, 规则与 generateCacheKeyInternal 的调试日志相同
+func RedactCacheKey(key string) string {
+	path, query, ok := strings.Cut(key, "?")
+	if !ok {
+		return key
+	}
+	// 按 vary 追加的 #hash 不属于查询参数
+	query, suffix, hashed := strings.Cut(query, "#")
+	key = path + "?" + redact.Current().Query(query)
+	if hashed {
+		key += "#" + suffix
+	}
 	return key
 }
 
-func GenerateCacheKeyFromParams(method, path string, query string, body []byte) string {
-	key := generateCacheKeyInternal(method, path, query, body)
-	util.Log().Info(fmt.Sprintf("Generated cache key from params - Method: %s, Path: %s, Key: %s", method, path, key))
-	return key
+func GenerateCacheKey(c *gin.Context) string {
+	return generateCacheKeyInternal(c, c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, getRequestBody(c))
+}
+
+func GenerateCacheKeyFromParams(ctx context.Context, method, path string, query string, body []byte) string {
+	return generateCacheKeyInternal(ctx, method, path, query, body)
 }
 
-func generateCacheKeyInternal(method, path, query string, body []byte) string {
-	version := "v1:"
-	key := version + path
+// generateCacheKeyInternal 生成缓存 key, 调试日志中的查询参数和请求体按 redact 规则隐藏敏感字段
+func generateCacheKeyInternal(ctx context.Context, method, path, query string, body []byte) string {
+	key := cacheKeyVersion + ":" + path
 
 	if method == "GET" {
 		if query != "" {
@@ -281,18 +447,17 @@ func generateCacheKeyInternal(method, path, query string, body []byte) string {
 			// 规范化 JSON
 			normalizedBody, err := normalizeJSON(body)
 			if err != nil {
-				util.Log().Error(fmt.Sprintf("Failed to normalize JSON body: %s", err.Error()))
+				util.LogCtx(ctx).Debug("Failed to normalize JSON body: %s", err.Error())
 				normalizedBody = body // 如果规范化失败，使用原始 body
 			}
 
 			hash := sha256.Sum256(normalizedBody)
 			key = key + ":" + hex.EncodeToString(hash[:])
-			util.Log().Info(fmt.Sprintf("Normalized body: %s", string(normalizedBody)))
-			util.Log().Info(fmt.Sprintf("POST request body hash: %s", hex.EncodeToString(hash[:])))
+			util.LogCtx(ctx).Debug("Normalized body: %s", redact.Current().Body(normalizedBody))
 		}
 	}
 
-	util.Log().Info(fmt.Sprintf("Final cache key: %s", key))
+	util.LogCtx(ctx).Debug("Generated cache key - Method: %s, Path: %s, Key: %s", method, path, RedactCacheKey(key))
 	return key
 }
 
@@ -303,7 +468,7 @@ func getRequestBody(c *gin.Context) []byte {
 
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		util.Log().Error("Failed to read request body: " + err.Error())
+		util.LogCtx(c).Error("Failed to read request body: " + err.Error())
 		return nil
 	}
 	// Restore the body for later use
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/internal/util"
+	"openapphub/pkg/cache"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	zapLogger = zap.NewNop()
+	util.BuildLogger(zapLogger)
+	os.Exit(m.Run())
+}
+
+func TestCacheMiddlewareConcurrentMisses(t *testing.T) {
+	store := cache.NewMemoryStore("test:")
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	r := gin.New()
+	r.GET("/slow", CacheMiddlewareWithStore(store, time.Minute), func(c *gin.Context) {
+		calls.Add(1)
+		<-release
+		c.Header("X-Multi", "a")
+		c.Writer.Header().Add("X-Multi", "b")
+		c.Data(http.StatusCreated, "text/plain; charset=utf-8", []byte("payload"))
+	})
+
+	const n = 8
+	recorders := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(w *httptest.ResponseRecorder) {
+			defer wg.Done()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		}(recorders[i])
+	}
+
+	// 给所有请求足够的时间加入同一个 singleflight 调用
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+	for i, w := range recorders {
+		if w.Code != http.StatusCreated {
+			t.Errorf("response %d: status %d, want %d", i, w.Code, http.StatusCreated)
+		}
+		if w.Body.String() != "payload" {
+			t.Errorf("response %d: body %q, want %q", i, w.Body.String(), "payload")
+		}
+		if got := w.Header().Values("X-Multi"); !slices.Equal(got, []string{"a", "b"}) {
+			t.Errorf("response %d: X-Multi %v, want [a b]", i, got)
+		}
+		if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+			t.Errorf("response %d: Content-Type %q", i, got)
+		}
+	}
+}
+
+func TestCacheMiddlewareReplaysCachedEntry(t *testing.T) {
+	store := cache.NewMemoryStore("test:")
+	var calls atomic.Int32
+
+	r := gin.New()
+	r.GET("/ping", CacheMiddlewareWithStore(store, time.Minute), func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusOK, gin.H{"msg": "Pong"})
+	})
+
+	first := httptest.NewRecorder()
+	r.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	// 缓存是异步写入的
+	deadline := time.Now().Add(time.Second)
+	key := generateCacheKeyInternal(context.Background(), http.MethodGet, "/ping", "", nil)
+	for {
+		if ok, _ := store.Exists(context.Background(), key); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("response was not cached")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	second := httptest.NewRecorder()
+	r.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+	if second.Header().Get("X-From-Cache") != "true" {
+		t.Errorf("X-From-Cache = %q, want true", second.Header().Get("X-From-Cache"))
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("cached body %q, want %q", second.Body.String(), first.Body.String())
+	}
+	if got := second.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestCacheMiddlewareDoesNotShareCookies(t *testing.T) {
+	store := cache.NewMemoryStore("test:")
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	r := gin.New()
+	r.POST("/login", CacheMiddlewareWithStore(store, time.Minute), func(c *gin.Context) {
+		calls.Add(1)
+		<-release
+		c.SetCookie("session", "secret", 60, "/", "", false, true)
+		c.String(http.StatusOK, "ok")
+	})
+
+	const n = 3
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", nil))
+		}()
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != n {
+		t.Fatalf("handler called %d times, want %d", got, n)
+	}
+}
+
+func TestRedactCacheKey(t *testing.T) {
+	tests := map[string]string{
+		"v1:/api/v1/items":                               "v1:/api/v1/items",
+		"v1:/api/v1/items?page=2&access_token=abc":       "v1:/api/v1/items?page=2&access_token=******",
+		"v1:/api/v1/items?access_token=abc#0123456789ab": "v1:/api/v1/items?access_token=******#0123456789ab",
+	}
+	for key, want := range tests {
+		if got := RedactCacheKey(key); got != want {
+			t.Errorf("RedactCacheKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"openapphub/internal/model"
+	"openapphub/pkg/cache"
+	"openapphub/pkg/i18n"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// 缓存 key 的可变维度
+const (
+	VaryQuery        = "query"   // GET 请求的查询参数
+	VaryBody         = "body"    // POST 请求体
+	VaryUser         = "user"    // 当前登录用户
+	VaryLocale       = "locale"  // 协商出的语言, 响应消息经过翻译的路由需要配置
+	VaryHeaderPrefix = "header:" // 指定请求头, 例如 header:Accept-Language
+)
+
+// CacheBypass 跳过缓存的规则, 满足任意一条即跳过
+type CacheBypass struct {
+	Headers       map[string]string `yaml:"headers" json:"headers,omitempty"`             // 请求头等于指定值
+	Query         []string          `yaml:"query" json:"query,omitempty"`                 // 存在指定查询参数
+	Authenticated bool              `yaml:"authenticated" json:"authenticated,omitempty"` // 已登录用户
+}
+
+// CachePolicy 单个路由的缓存策略
+type CachePolicy struct {
+	Route       string        `yaml:"route" json:"route"` // gin 路由模板, 支持 path.Match 通配符
+	Methods     []string      `yaml:"methods" json:"methods"`
+	TTL         time.Duration `yaml:"ttl" json:"ttl"`
+	Vary        []string      `yaml:"vary" json:"vary"`
+	MaxBodySize int           `yaml:"max_body_size" json:"max_body_size,omitempty"` // 超过该字节数的响应不缓存, 0 表示不限制
+	StatusCodes []int         `yaml:"status_codes" json:"status_codes,omitempty"`   // 为空时缓存所有 2xx 响应
+	Bypass      CacheBypass   `yaml:"bypass" json:"bypass"`
+}
+
+// defaultCachePolicy 与原先 CacheMiddleware 的行为一致
+func defaultCachePolicy(ttl time.Duration) *CachePolicy {
+	policy := &CachePolicy{TTL: ttl}
+	policy.applyDefaults()
+	return policy
+}
+
+func (p *CachePolicy) applyDefaults() {
+	if len(p.Methods) == 0 {
+		p.Methods = []string{http.MethodGet, http.MethodPost}
+	}
+	for i, method := range p.Methods {
+		p.Methods[i] = strings.ToUpper(method)
+	}
+	if p.Vary == nil {
+		p.Vary = []string{VaryQuery, VaryBody}
+	}
+	if p.Bypass.Headers == nil {
+		p.Bypass.Headers = map[string]string{"X-Bypass-Cache": "true"}
+	}
+}
+
+func (p *CachePolicy) validate() error {
+	if p.Route == "" {
+		return fmt.Errorf("cache policy without route")
+	}
+	if _, err := path.Match(p.Route, "/"); err != nil {
+		return fmt.Errorf("cache policy %s: invalid route pattern: %w", p.Route, err)
+	}
+	if p.TTL <= 0 {
+		return fmt.Errorf("cache policy %s: ttl must be positive", p.Route)
+	}
+	for _, method := range p.Methods {
+		if method != http.MethodGet && method != http.MethodPost {
+			return fmt.Errorf("cache policy %s: method %s cannot be cached", p.Route, method)
+		}
+	}
+	for _, vary := range p.Vary {
+		if vary != VaryQuery && vary != VaryBody && vary != VaryUser && vary != VaryLocale &&
+			!(strings.HasPrefix(vary, VaryHeaderPrefix) && len(vary) > len(VaryHeaderPrefix)) {
+			return fmt.Errorf("cache policy %s: unknown vary dimension %s", p.Route, vary)
+		}
+	}
+	for _, code := range p.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("cache policy %s: invalid status code %d", p.Route, code)
+		}
+	}
+	if p.MaxBodySize < 0 {
+		return fmt.Errorf("cache policy %s: max_body_size must not be negative", p.Route)
+	}
+	return nil
+}
+
+func (p *CachePolicy) matches(method, route string) bool {
+	if !slices.Contains(p.Methods, method) {
+		return false
+	}
+	if p.Route == route {
+		return true
+	}
+	ok, _ := path.Match(p.Route, route)
+	return ok
+}
+
+func (p *CachePolicy) bypassed(c *gin.Context) bool {
+	for name, value := range p.Bypass.Headers {
+		if c.GetHeader(name) == value {
+			return true
+		}
+	}
+	query := c.Request.URL.Query()
+	for name := range query {
+		if slices.Contains(p.Bypass.Query, name) {
+			return true
+		}
+	}
+	if p.Bypass.Authenticated {
+		if user, _ := c.Get("user"); user != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// storable 判断响应是否可以写入缓存
+func (p *CachePolicy) storable(response *CachedResponse) bool {
+	if p.MaxBodySize > 0 && len(response.Data) > p.MaxBodySize {
+		return false
+	}
+	if len(p.StatusCodes) > 0 {
+		return slices.Contains(p.StatusCodes, response.Status)
+	}
+	return response.Status >= 200 && response.Status < 300
+}
+
+// cacheKey 按策略的可变维度生成缓存 key.
+// 只包含 query/body 维度时与 GenerateCacheKeyFromParams 生成的 key 相同, 管理接口可以直接定位
+func (p *CachePolicy) cacheKey(c *gin.Context) string {
+	var query string
+	var body []byte
+	if slices.Contains(p.Vary, VaryQuery) {
+		query = c.Request.URL.RawQuery
+	}
+	if slices.Contains(p.Vary, VaryBody) {
+		body = getRequestBody(c)
+	}
+	key := generateCacheKeyInternal(c, c.Request.Method, c.Request.URL.Path, query, body)
+
+	var extra []string
+	for _, vary := range p.Vary {
+		switch {
+		case vary == VaryUser:
+			extra = append(extra, "user="+currentUserID(c))
+		case vary == VaryLocale:
+			extra = append(extra, "locale="+i18n.Locale(c))
+		case strings.HasPrefix(vary, VaryHeaderPrefix):
+			name := strings.TrimPrefix(vary, VaryHeaderPrefix)
+			extra = append(extra, vary+"="+c.GetHeader(name))
+		}
+	}
+	if len(extra) > 0 {
+		hash := sha256.Sum256([]byte(strings.Join(extra, "\n")))
+		key = key + "#" + hex.EncodeToString(hash[:8])
+	}
+	return key
+}
+
+func currentUserID(c *gin.Context) string {
+	if user, _ := c.Get("user"); user != nil {
+		if u, ok := user.(*model.User); ok {
+			return fmt.Sprint(u.ID)
+		}
+	}
+	return ""
+}
+
+// CachePolicySet 从配置文件加载的全部缓存策略
+type CachePolicySet struct {
+	Routes []CachePolicy `yaml:"routes" json:"routes"`
+	path   string
+}
+
+// Match 返回第一个匹配请求方法和路由模板的策略
+func (s *CachePolicySet) Match(method, route string) *CachePolicy {
+	if route == "" {
+		return nil
+	}
+	for i := range s.Routes {
+		if s.Routes[i].matches(method, route) {
+			return &s.Routes[i]
+		}
+	}
+	return nil
+}
+
+// ParseCachePolicies 解析并校验缓存策略
+func ParseCachePolicies(data []byte) (*CachePolicySet, error) {
+	var set CachePolicySet
+	if err := yaml.UnmarshalStrict(data, &set); err != nil {
+		return nil, err
+	}
+	for i := range set.Routes {
+		set.Routes[i].applyDefaults()
+		if err := set.Routes[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &set, nil
+}
+
+var cachePolicies atomic.Pointer[CachePolicySet]
+
+// LoadCachePolicies 读取缓存策略文件并原子替换当前策略, 解析失败时保留原有策略
+func LoadCachePolicies(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	set, err := ParseCachePolicies(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	set.path = path
+	cachePolicies.Store(set)
+	return nil
+}
+
+// ReloadCachePolicies 重新读取上次加载的策略文件
+func ReloadCachePolicies() error {
+	current := cachePolicies.Load()
+	if current == nil || current.path == "" {
+		return fmt.Errorf("cache policies were not loaded from a file")
+	}
+	return LoadCachePolicies(current.path)
+}
+
+// SetCachePolicies 直接替换当前策略, 主要用于测试
+func SetCachePolicies(set *CachePolicySet) {
+	cachePolicies.Store(set)
+}
+
+// CurrentCachePolicies 返回当前生效的缓存策略
+func CurrentCachePolicies() *CachePolicySet {
+	if set := cachePolicies.Load(); set != nil {
+		return set
+	}
+	return &CachePolicySet{}
+}
+
+// Cache 按配置文件中的策略缓存响应, 在路由组上的认证、IP 过滤和限流中间件之后使用, 缓存命中时不会跳过它们.
+// 按用户返回不同内容的路由必须配置 vary: [user] 或 bypass.authenticated
+func Cache() gin.HandlerFunc {
+	return CacheWithStore(cache.Default())
+}
+
+// CacheWithStore 使用指定的缓存后端按策略缓存响应
+func CacheWithStore(store cache.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := CurrentCachePolicies().Match(c.Request.Method, c.FullPath())
+		if policy == nil || noCacheRoute(c) {
+			c.Next()
+			return
+		}
+		serveCached(c, store, policy)
+	}
+}
+
+// NoCache 标记路由不能被缓存, 即使 cache.yaml 中有匹配的策略.
+// 登录、注销、刷新令牌等设置认证状态的路由, 以及管理接口等有副作用的路由都需要加上
+func NoCache() gin.HandlerFunc {
+	return noCacheMarker
+}
+
+// noCacheMarker 只作为标记, 不做任何处理
+func noCacheMarker(*gin.Context) {}
+
+// noCacheName 与 gin 的 HandlerNames 使用相同的方式取函数名
+var noCacheName = runtime.FuncForPC(reflect.ValueOf(noCacheMarker).Pointer()).Name()
+
+// noCacheRoute 路由的处理链中是否包含 NoCache
+func noCacheRoute(c *gin.Context) bool {
+	return slices.Contains(c.HandlerNames(), noCacheName)
+}
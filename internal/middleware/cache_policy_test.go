@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/internal/util"
+	"openapphub/pkg/cache"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseCachePolicies(t *testing.T) {
+	set, err := ParseCachePolicies([]byte(`
+routes:
+  - route: /api/v1/items/:id
+    methods: [get]
+    ttl: 30s
+    vary: [query, "header:Accept-Language"]
+    bypass:
+      query: [nocache]
+  - route: /api/v1/public/*
+    ttl: 1m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := set.Match(http.MethodGet, "/api/v1/items/:id")
+	if policy == nil || policy.TTL != 30*time.Second {
+		t.Fatalf("Match items = %+v", policy)
+	}
+	if set.Match(http.MethodPost, "/api/v1/items/:id") != nil {
+		t.Error("POST should not match a GET-only policy")
+	}
+	if p := set.Match(http.MethodPost, "/api/v1/public/feed"); p == nil || p.Route != "/api/v1/public/*" {
+		t.Errorf("wildcard route did not match: %+v", p)
+	}
+	if set.Match(http.MethodGet, "/api/v1/other") != nil {
+		t.Error("unexpected match for unknown route")
+	}
+
+	for _, invalid := range []string{
+		"routes:\n  - route: /a\n",
+		"routes:\n  - route: /a\n    ttl: 1m\n    methods: [DELETE]\n",
+		"routes:\n  - route: /a\n    ttl: 1m\n    vary: [cookie]\n",
+		"routes:\n  - route: /a\n    ttl: 1m\n    unknown: true\n",
+	} {
+		if _, err := ParseCachePolicies([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestCacheAppliesRoutePolicies(t *testing.T) {
+	set, err := ParseCachePolicies([]byte(`
+routes:
+  - route: /items/:id
+    methods: [GET]
+    ttl: 1m
+    vary: ["header:Accept-Language"]
+    max_body_size: 16
+    bypass:
+      query: [nocache]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetCachePolicies(set)
+	defer SetCachePolicies(nil)
+
+	store := cache.NewMemoryStore("test:")
+	var calls atomic.Int32
+	r := gin.New()
+	r.Use(CacheWithStore(store))
+	r.GET("/items/:id", func(c *gin.Context) {
+		calls.Add(1)
+		c.String(http.StatusOK, c.Param("id")+c.GetHeader("Accept-Language"))
+	})
+	r.GET("/uncached", func(c *gin.Context) {
+		calls.Add(1)
+		c.String(http.StatusOK, "ok")
+	})
+
+	request := func(path, lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	waitCached := func() {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if keys, _, _ := store.Scan(context.Background(), "v1:", "", 100); len(keys) > 0 {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	request("/items/1", "en")
+	waitCached()
+	if w := request("/items/1", "en"); w.Header().Get("X-From-Cache") != "true" {
+		t.Errorf("second request was not served from cache")
+	}
+	if w := request("/items/1", "zh"); w.Header().Get("X-From-Cache") != "" || w.Body.String() != "1zh" {
+		t.Errorf("vary header ignored: %q %q", w.Header().Get("X-From-Cache"), w.Body.String())
+	}
+	if w := request("/items/1?nocache=1", "en"); w.Header().Get("X-From-Cache") != "" {
+		t.Errorf("bypass query ignored")
+	}
+	request("/uncached", "en")
+	request("/uncached", "en")
+	if got := calls.Load(); got != 5 {
+		t.Errorf("handler called %d times, want 5", got)
+	}
+}
+
+func TestCacheSkipsNoCacheRoutes(t *testing.T) {
+	set, err := ParseCachePolicies([]byte("routes:\n  - route: /user/*\n    ttl: 1m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetCachePolicies(set)
+	defer SetCachePolicies(nil)
+
+	store := cache.NewMemoryStore("test:")
+	var calls atomic.Int32
+	r := gin.New()
+	r.Use(CacheWithStore(store))
+	r.POST("/user/login", NoCache(), func(c *gin.Context) {
+		calls.Add(1)
+		c.String(http.StatusOK, "token")
+	})
+
+	for i := 0; i < 2; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/login", nil))
+		// 等待异步的缓存写入
+		_ = util.WaitBackground(context.Background())
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2: NoCache route was served from cache", calls.Load())
+	}
+	if keys, _, _ := store.Scan(context.Background(), "", "", 10); len(keys) != 0 {
+		t.Errorf("NoCache route was stored: %v", keys)
+	}
+}
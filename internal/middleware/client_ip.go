@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"openapphub/pkg/proxyproto"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 可信代理用来传递客户端地址的请求头
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// clientIPKey 解析出的客户端地址在 gin.Context 中的 key
+const clientIPKey = "client_ip"
+
+// ProxyConfig 可信代理配置
+type ProxyConfig struct {
+	// TrustedProxies 可信代理的网段, 只有来自这些地址的代理请求头才会被采信
+	TrustedProxies []netip.Prefix
+	// Headers 按顺序尝试的代理请求头, 第一个能解析出地址的生效
+	Headers []string
+	// ProxyProtocol 监听器是否解析可信代理发送的 PROXY protocol 头
+	ProxyProtocol bool
+}
+
+// proxyConfig 默认不信任任何代理, 直接使用连接的对端地址
+var proxyConfig = ProxyConfig{Headers: []string{HeaderXForwardedFor, HeaderXRealIP}}
+
+// ConfigureProxies 设置可信代理配置
+func ConfigureProxies(conf ProxyConfig) error {
+	for i, header := range conf.Headers {
+		header = http.CanonicalHeaderKey(header)
+		switch header {
+		case HeaderForwarded, HeaderXForwardedFor, http.CanonicalHeaderKey(HeaderXRealIP):
+		default:
+			return fmt.Errorf("unsupported proxy header: %s", header)
+		}
+		conf.Headers[i] = header
+	}
+	proxyConfig = conf
+	return nil
+}
+
+// TrustedProxy 判断地址是否属于可信代理
+func TrustedProxy(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range proxyConfig.TrustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RealIP 每个请求只解析一次客户端地址, 必须在 RequestID 之后、其它中间件之前使用.
+// 解析结果会写回 Request.RemoteAddr, 因此 c.ClientIP() 和访问日志得到的也是真实地址
+func RealIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := resolveClientIP(c.Request)
+		c.Set(clientIPKey, ip)
+		port := "0"
+		if _, p, err := net.SplitHostPort(c.Request.RemoteAddr); err == nil {
+			port = p
+		}
+		c.Request.RemoteAddr = net.JoinHostPort(ip, port)
+		c.Next()
+	}
+}
+
+// ClientIP 返回 RealIP 解析出的客户端地址
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	return resolveClientIP(c.Request)
+}
+
+// resolveClientIP 连接来自可信代理时, 从右向左跳过可信代理, 取第一个不可信的地址作为客户端地址
+func resolveClientIP(r *http.Request) string {
+	remote, err := parseAddr(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	if !TrustedProxy(remote) {
+		return remote.String()
+	}
+
+	for _, header := range proxyConfig.Headers {
+		var chain []string
+		switch header {
+		case HeaderForwarded:
+			chain = forwardedFor(r.Header.Values(HeaderForwarded))
+		case HeaderXForwardedFor:
+			for _, value := range r.Header.Values(HeaderXForwardedFor) {
+				chain = append(chain, splitList(value)...)
+			}
+		default:
+			chain = splitList(r.Header.Get(HeaderXRealIP))
+		}
+		if len(chain) == 0 {
+			continue
+		}
+
+		client := remote
+		for i := len(chain) - 1; i >= 0; i-- {
+			ip, err := parseAddr(chain[i])
+			if err != nil {
+				// 无法识别的地址(例如 "unknown"), 使用最后一个可信代理记录的上一跳
+				break
+			}
+			client = ip
+			if !TrustedProxy(ip) {
+				break
+			}
+		}
+		return client.String()
+	}
+	return remote.String()
+}
+
+// forwardedFor 提取 RFC 7239 Forwarded 请求头中的 for= 参数
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					chain = append(chain, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return chain
+}
+
+// parseAddr 解析 "ip", "ip:port", "[ipv6]" 或 "[ipv6]:port"
+func parseAddr(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if ip, err := netip.ParseAddr(strings.Trim(value, "[]")); err == nil {
+		return ip.Unmap(), nil
+	}
+	addrPort, err := netip.ParseAddrPort(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addrPort.Addr().Unmap(), nil
+}
+
+// parsePrefix 解析网段, 单个地址视为只包含自身的网段
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return prefix, fmt.Errorf("invalid trusted proxy %s: %w", value, err)
+		}
+		return prefix.Masked(), nil
+	}
+	ip, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %s: %w", value, err)
+	}
+	ip = ip.Unmap()
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// splitList 拆分逗号分隔的列表并去掉空白项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ProxyListener 开启 PROXY protocol 时包装监听器, 只解析来自可信代理的 PROXY 头
+func ProxyListener(l net.Listener) net.Listener {
+	if !proxyConfig.ProxyProtocol {
+		return l
+	}
+	return &proxyproto.Listener{
+		Listener: l,
+		Trusted: func(addr net.Addr) bool {
+			ip, err := parseAddr(addr.String())
+			return err == nil && TrustedProxy(ip)
+		},
+		Timeout: 5 * time.Second,
+	}
+}
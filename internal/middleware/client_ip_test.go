@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	saved := proxyConfig
+	defer func() { proxyConfig = saved }()
+	if err := ConfigureProxies(ProxyConfig{
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		Headers:        []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"untrusted peer ignores headers", "198.51.100.1:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "198.51.100.1"},
+		{"spoofed entries left of the client are ignored", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.9, 10.0.0.3"}, "203.0.113.9"},
+		{"forwarded takes priority", "10.0.0.2:1234", map[string]string{"Forwarded": `for="[2001:db8::17]:4711";proto=https`, "X-Forwarded-For": "1.2.3.4"}, "2001:db8::17"},
+		{"real ip", "10.0.0.2:1234", map[string]string{"X-Real-IP": "203.0.113.5"}, "203.0.113.5"},
+		{"unknown entry stops at the last proxy", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "unknown, 10.0.0.3"}, "10.0.0.3"},
+		{"no headers", "10.0.0.2:1234", nil, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := resolveClientIP(req); got != tt.want {
+				t.Fatalf("resolveClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"net/http"
+	"openapphub/internal/model"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 请求优先级, 队列满时优先丢弃低优先级的请求
+const (
+	PriorityAnonymous     = 0
+	PriorityAuthenticated = 1
+	PriorityInternal      = 2
+)
+
+// ConcurrencyPolicy 限制同时处理的请求数.
+// 超过 MaxInFlight 的请求按优先级排队, 队列已满或等待超时时返回 503
+type ConcurrencyPolicy struct {
+	Name        string
+	MaxInFlight int           // 同时处理的最大请求数
+	MaxQueue    int           // 排队等待的最大请求数, 0 表示不排队
+	MaxWait     time.Duration // 最长排队时间
+	// Priority 返回请求的优先级, 为 nil 时使用 RequestPriority
+	Priority func(c *gin.Context) int
+}
+
+// RequestPriority 内部用户优先于登录用户, 登录用户优先于匿名用户.
+// 需要在 CurrentUser 之后使用
+func RequestPriority(c *gin.Context) int {
+	user := rateLimitUser(c)
+	switch {
+	case user == nil:
+		return PriorityAnonymous
+	case user.Tier == model.TierInternal:
+		return PriorityInternal
+	default:
+		return PriorityAuthenticated
+	}
+}
+
+// ConcurrencyStats 单个策略的并发统计, 只统计当前实例
+type ConcurrencyStats struct {
+	InFlight int   `json:"in_flight"`
+	Queued   int   `json:"queued"`
+	Shed     int64 `json:"shed"`     // 队列已满被拒绝或被更高优先级挤出的请求
+	Timeouts int64 `json:"timeouts"` // 排队超时的请求
+}
+
+var concurrencyLimiters = struct {
+	sync.Mutex
+	policies map[string]*concurrencyLimiter
+}{policies: make(map[string]*concurrencyLimiter)}
+
+// ConcurrencyStatsSnapshot 返回所有策略的并发统计
+func ConcurrencyStatsSnapshot() map[string]ConcurrencyStats {
+	concurrencyLimiters.Lock()
+	defer concurrencyLimiters.Unlock()
+
+	snapshot := make(map[string]ConcurrencyStats, len(concurrencyLimiters.policies))
+	for name, l := range concurrencyLimiters.policies {
+		l.mu.Lock()
+		snapshot[name] = l.stats
+		l.mu.Unlock()
+	}
+	return snapshot
+}
+
+// ConcurrencyLimit 返回限制并发的中间件
+func ConcurrencyLimit(policy ConcurrencyPolicy) gin.HandlerFunc {
+	if policy.MaxInFlight <= 0 {
+		panic("concurrency policy " + policy.Name + ": max in flight must be positive")
+	}
+	priority := policy.Priority
+	if priority == nil {
+		priority = RequestPriority
+	}
+	l := &concurrencyLimiter{policy: policy}
+
+	concurrencyLimiters.Lock()
+	concurrencyLimiters.policies[policy.Name] = l
+	concurrencyLimiters.Unlock()
+
+	return func(c *gin.Context) {
+		if err := l.acquire(c.Request.Context(), priority(c)); err != nil {
+			retryAfter := max(int64(math.Ceil(policy.MaxWait.Seconds())), 1)
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, serializer.Track(c, serializer.Response{
+				Code: serializer.CodeServerBusy,
+				Msg:  i18n.T(c, "Common.ServerBusy"),
+			}))
+			RequestLogger(c).Warn("Request shed",
+				zap.String("policy", policy.Name),
+				zap.String("reason", err.Error()))
+			return
+		}
+		defer l.release()
+		c.Next()
+	}
+}
+
+// shedError 请求被拒绝的原因
+type shedError string
+
+func (e shedError) Error() string {
+	return string(e)
+}
+
+const (
+	errQueueFull  shedError = "queue full"
+	errQueueWait  shedError = "queue wait timeout"
+	errPreempted  shedError = "preempted by higher priority request"
+	errClientGone shedError = "client canceled while queued"
+)
+
+type waiter struct {
+	priority int
+	seq      uint64
+	index    int
+	ready    chan struct{}
+	granted  bool
+	err      error
+}
+
+// waitQueue 按优先级从高到低、同优先级先到先得排序的堆
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	w.index = -1
+	return w
+}
+
+type concurrencyLimiter struct {
+	policy ConcurrencyPolicy
+
+	mu    sync.Mutex
+	queue waitQueue
+	seq   uint64
+	stats ConcurrencyStats
+}
+
+func (l *concurrencyLimiter) acquire(ctx context.Context, priority int) error {
+	l.mu.Lock()
+	if l.stats.InFlight < l.policy.MaxInFlight && len(l.queue) == 0 {
+		l.stats.InFlight++
+		l.mu.Unlock()
+		return nil
+	}
+
+	if len(l.queue) >= l.policy.MaxQueue {
+		// 队列已满时挤掉优先级最低且最晚到达的请求
+		lowest := l.lowest()
+		if lowest == nil || lowest.priority >= priority {
+			l.stats.Shed++
+			l.mu.Unlock()
+			return errQueueFull
+		}
+		heap.Remove(&l.queue, lowest.index)
+		lowest.err = errPreempted
+		close(lowest.ready)
+		l.stats.Shed++
+	}
+
+	l.seq++
+	w := &waiter{priority: priority, seq: l.seq, ready: make(chan struct{})}
+	heap.Push(&l.queue, w)
+	l.stats.Queued = len(l.queue)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.policy.MaxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+	case <-timer.C:
+		err = errQueueWait
+	case <-ctx.Done():
+		err = errClientGone
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.granted {
+		// 超时和获得执行权同时发生时, 以获得执行权为准
+		return nil
+	}
+	if w.err != nil {
+		return w.err
+	}
+	heap.Remove(&l.queue, w.index)
+	l.stats.Queued = len(l.queue)
+	if err == errQueueWait {
+		l.stats.Timeouts++
+	}
+	return err
+}
+
+// release 把执行权直接交给队首的请求, 队列为空时释放名额
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.queue) > 0 {
+		w := heap.Pop(&l.queue).(*waiter)
+		w.granted = true
+		close(w.ready)
+		l.stats.Queued = len(l.queue)
+		return
+	}
+	l.stats.InFlight--
+}
+
+// lowest 返回队列中优先级最低且最晚到达的请求, 调用方需持有锁
+func (l *concurrencyLimiter) lowest() *waiter {
+	var lowest *waiter
+	for _, w := range l.queue {
+		if lowest == nil || w.priority < lowest.priority ||
+			(w.priority == lowest.priority && w.seq > lowest.seq) {
+			lowest = w
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterPriority(t *testing.T) {
+	l := &concurrencyLimiter{policy: ConcurrencyPolicy{MaxInFlight: 1, MaxQueue: 1, MaxWait: time.Second}}
+	ctx := context.Background()
+	if err := l.acquire(ctx, PriorityAnonymous); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan error, 2)
+	go func() { results <- l.acquire(ctx, PriorityAnonymous) }()
+	waitQueued(t, l, 1)
+
+	// The queue is full: an authenticated request takes the anonymous request's place
+	go func() { results <- l.acquire(ctx, PriorityAuthenticated) }()
+	if err := <-results; err != errPreempted {
+		t.Fatalf("anonymous request: %v, want %v", err, errPreempted)
+	}
+	waitQueued(t, l, 1)
+
+	// Another anonymous request is shed immediately
+	if err := l.acquire(ctx, PriorityAnonymous); err != errQueueFull {
+		t.Fatalf("shed request: %v, want %v", err, errQueueFull)
+	}
+
+	l.release()
+	if err := <-results; err != nil {
+		t.Fatalf("authenticated request: %v", err)
+	}
+	l.release()
+	if l.stats.InFlight != 0 || l.stats.Shed != 2 {
+		t.Fatalf("stats = %+v", l.stats)
+	}
+}
+
+func TestConcurrencyLimiterTimeout(t *testing.T) {
+	l := &concurrencyLimiter{policy: ConcurrencyPolicy{MaxInFlight: 1, MaxQueue: 1, MaxWait: 10 * time.Millisecond}}
+	ctx := context.Background()
+	if err := l.acquire(ctx, PriorityAnonymous); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire(ctx, PriorityInternal); err != errQueueWait {
+		t.Fatalf("queued request: %v, want %v", err, errQueueWait)
+	}
+	if l.stats.Queued != 0 || l.stats.Timeouts != 1 {
+		t.Fatalf("stats = %+v", l.stats)
+	}
+}
+
+func waitQueued(t *testing.T, l *concurrencyLimiter, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		l.mu.Lock()
+		queued := len(l.queue)
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("queue never reached %d waiters", n)
+}
@@ -2,17 +2,54 @@ package middleware
 
 import (
 	"regexp"
+	"sync/atomic"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// corsHandler 当前生效的跨域中间件, 修改允许的域名时整体替换
+var corsHandler atomic.Pointer[gin.HandlerFunc]
+
+// ValidateCorsOrigins 检查允许跨域的域名格式, 支持 * 通配符, 例如 https://*.example.com
+func ValidateCorsOrigins(origins []string) error {
+	config := corsConfig(origins)
+	return config.Validate()
+}
+
+// ConfigureCors 设置允许跨域的域名, 对已经创建的中间件立即生效.
+// origins 为空时生产环境只允许 http://www.example.com, 其它环境允许本地地址
+func ConfigureCors(origins []string) error {
+	config := corsConfig(origins)
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	handler := cors.New(config)
+	corsHandler.Store(&handler)
+	return nil
+}
+
 // Cors 跨域配置
 func Cors() gin.HandlerFunc {
+	if corsHandler.Load() == nil {
+		_ = ConfigureCors(nil)
+	}
+	return func(c *gin.Context) {
+		(*corsHandler.Load())(c)
+	}
+}
+
+func corsConfig(origins []string) cors.Config {
 	config := cors.DefaultConfig()
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Cookie"}
-	if gin.Mode() == gin.ReleaseMode {
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Cookie", RequestIDHeader}
+	// 允许浏览器读取限流响应头和请求 ID
+	config.ExposeHeaders = []string{"RateLimit", "RateLimit-Policy", "Retry-After",
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", RequestIDHeader}
+	if len(origins) > 0 {
+		config.AllowOrigins = origins
+		config.AllowWildcard = true
+	} else if gin.Mode() == gin.ReleaseMode {
 		// 生产环境需要配置跨域域名，否则403
 		config.AllowOrigins = []string{"http://www.example.com"}
 	} else {
@@ -28,5 +65,5 @@ func Cors() gin.HandlerFunc {
 		}
 	}
 	config.AllowCredentials = true
-	return cors.New(config)
+	return config
 }
@@ -0,0 +1,339 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"openapphub/pkg/cache"
+	"openapphub/pkg/geoip"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IP 过滤规则列表
+const (
+	IPFilterAllow = "allow"
+	IPFilterDeny  = "deny"
+)
+
+var (
+	// ErrUnknownIPFilter 没有注册该名称的过滤器
+	ErrUnknownIPFilter = errors.New("unknown ip filter")
+	// ErrInvalidIPFilterEntry 规则列表或网段格式错误
+	ErrInvalidIPFilterEntry = errors.New("invalid ip filter entry")
+)
+
+// ipFilterRefreshInterval 运行时规则的本地缓存时间
+const ipFilterRefreshInterval = 10 * time.Second
+
+// IPFilterPolicy 按网段和国家过滤请求.
+// 命中任意拒绝规则, 或配置了允许规则但一条都没有命中时返回 403
+type IPFilterPolicy struct {
+	// Name 运行时规则保存在 <Name>:allow 和 <Name>:deny 两个集合中, 命名空间见 ipFilterNamespace
+	Name string
+	// Allow 允许的 IP 或网段, 与运行时规则都为空时允许所有地址
+	Allow []string
+	// Deny 拒绝的 IP 或网段
+	Deny []string
+	// AllowCountries 允许的 ISO 国家代码, 为空时不限制, 查不到国家的地址会被拒绝
+	AllowCountries []string
+	// DenyCountries 拒绝的 ISO 国家代码
+	DenyCountries []string
+	// Store 保存运行时规则的缓存后端, 为 nil 时使用 cache.Default() 的连接, 命名空间见 ipFilterNamespace
+	Store cache.Store
+}
+
+// ipRules 解析后的网段规则
+type ipRules struct {
+	allow    []netip.Prefix
+	deny     []netip.Prefix
+	loadedAt time.Time
+}
+
+type ipFilter struct {
+	policy  IPFilterPolicy
+	store   cache.Store
+	static  ipRules
+	runtime atomic.Pointer[ipRules]
+	loading sync.Mutex
+}
+
+var (
+	// geoIP 国家规则使用的数据库, 未配置时不能使用国家规则
+	geoIP *geoip.Reader
+
+	ipFiltersMu sync.Mutex
+	ipFilters   = make(map[string]*ipFilter)
+)
+
+// ConfigureGeoIP 加载 MaxMind 格式的国家数据库, path 为空时不加载
+func ConfigureGeoIP(path string) error {
+	if path == "" {
+		geoIP = nil
+		return nil
+	}
+	reader, err := geoip.Open(path)
+	if err != nil {
+		return err
+	}
+	geoIP = reader
+	return nil
+}
+
+// IPFilter 返回按策略过滤请求的中间件, 需要在 RealIP 之后、Cache 之前使用
+func IPFilter(policy IPFilterPolicy) gin.HandlerFunc {
+	f := &ipFilter{policy: policy, store: policy.Store}
+	if f.store == nil {
+		f.store = cache.Default()
+	}
+	// 运行时规则与响应缓存使用不同的命名空间, 清理缓存时不会删除
+	f.store = f.store.WithNamespace(f.store.Namespace() + ipFilterNamespace)
+	var err error
+	if f.static.allow, err = parsePrefixes(policy.Allow); err != nil {
+		panic(err)
+	}
+	if f.static.deny, err = parsePrefixes(policy.Deny); err != nil {
+		panic(err)
+	}
+	if (len(policy.AllowCountries) > 0 || len(policy.DenyCountries) > 0) && geoIP == nil {
+		panic(fmt.Sprintf("ip filter %s: country rules require a GeoIP database", policy.Name))
+	}
+
+	ipFiltersMu.Lock()
+	ipFilters[policy.Name] = f
+	ipFiltersMu.Unlock()
+
+	return func(c *gin.Context) {
+		clientIP := ClientIP(c)
+		ip, err := netip.ParseAddr(clientIP)
+		if err != nil {
+			// 无法解析的地址不能证明在白名单内, 有白名单时拒绝, 只有黑名单时放行
+			if f.allowListed(c) {
+				f.deny(c, clientIP, "unparsable client address")
+				return
+			}
+			c.Next()
+			return
+		}
+		if reason := f.check(c, ip); reason != "" {
+			f.deny(c, ip.String(), reason)
+			return
+		}
+		c.Next()
+	}
+}
+
+// deny 记录日志并返回 403
+func (f *ipFilter) deny(c *gin.Context, ip, reason string) {
+	RequestLogger(c).Warn("Request blocked by ip filter",
+		zap.String("filter", f.policy.Name),
+		zap.String("ip", ip),
+		zap.String("reason", reason))
+	c.AbortWithStatusJSON(http.StatusForbidden, serializer.Track(c, serializer.Response{
+		Code: serializer.CodeNoRightErr,
+		Msg:  i18n.T(c, "Common.AccessDenied"),
+	}))
+}
+
+// allowListed 策略是否只允许白名单内的网段或国家访问
+func (f *ipFilter) allowListed(ctx context.Context) bool {
+	return len(f.policy.AllowCountries) > 0 || len(f.static.allow) > 0 || len(f.rules(ctx).allow) > 0
+}
+
+// check 返回拒绝的原因, 允许访问时返回空字符串
+func (f *ipFilter) check(ctx context.Context, ip netip.Addr) string {
+	ip = ip.Unmap()
+	runtime := f.rules(ctx)
+	if containsAddr(f.static.deny, ip) || containsAddr(runtime.deny, ip) {
+		return "denied network"
+	}
+
+	if len(f.policy.AllowCountries) > 0 || len(f.policy.DenyCountries) > 0 {
+		country, err := geoIP.Country(ip)
+		if err != nil {
+			RequestLogger(ctx).Error("GeoIP lookup failed", zap.String("ip", ip.String()), zap.Error(err))
+		}
+		if country != "" && slices.Contains(f.policy.DenyCountries, country) {
+			return "denied country " + country
+		}
+		if len(f.policy.AllowCountries) > 0 && !slices.Contains(f.policy.AllowCountries, country) {
+			return "country not allowed " + country
+		}
+	}
+
+	if len(f.static.allow) > 0 || len(runtime.allow) > 0 {
+		if !containsAddr(f.static.allow, ip) && !containsAddr(runtime.allow, ip) {
+			return "network not allowed"
+		}
+	}
+	return ""
+}
+
+// rules 返回运行时规则, 过期后由一个请求负责刷新, 其它请求继续使用旧规则
+func (f *ipFilter) rules(ctx context.Context) *ipRules {
+	current := f.runtime.Load()
+	if current != nil && time.Since(current.loadedAt) < ipFilterRefreshInterval {
+		return current
+	}
+	if current == nil {
+		f.loading.Lock()
+	} else if !f.loading.TryLock() {
+		return current
+	}
+	defer f.loading.Unlock()
+
+	if latest := f.runtime.Load(); latest != current {
+		return latest
+	}
+	rules, err := f.load(ctx)
+	if err != nil {
+		RequestLogger(ctx).Error("Failed to load ip filter rules", zap.String("filter", f.policy.Name), zap.Error(err))
+		if current == nil {
+			current = &ipRules{}
+		}
+		// 加载失败时沿用旧规则, 等下一个周期再重试
+		rules = &ipRules{allow: current.allow, deny: current.deny}
+	}
+	rules.loadedAt = time.Now()
+	f.runtime.Store(rules)
+	return rules
+}
+
+func (f *ipFilter) load(ctx context.Context) (*ipRules, error) {
+	rules := &ipRules{}
+	for _, list := range []string{IPFilterAllow, IPFilterDeny} {
+		members, err := f.store.SMembers(ctx, ipFilterKey(f.policy.Name, list))
+		if err != nil {
+			return nil, err
+		}
+		prefixes := make([]netip.Prefix, 0, len(members))
+		for _, member := range members {
+			prefix, err := parsePrefix(member)
+			if err != nil {
+				GetZapLogger().Warn("Ignoring invalid ip filter entry", zap.String("filter", f.policy.Name), zap.Error(err))
+				continue
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		if list == IPFilterAllow {
+			rules.allow = prefixes
+		} else {
+			rules.deny = prefixes
+		}
+	}
+	return rules, nil
+}
+
+// ipFilterNamespace 运行时规则在缓存命名空间下的子命名空间
+const ipFilterNamespace = "ipfilter:"
+
+func ipFilterKey(name, list string) string {
+	return name + ":" + list
+}
+
+// IPFilterInfo 过滤器的静态规则和运行时规则
+type IPFilterInfo struct {
+	Name           string   `json:"name"`
+	Allow          []string `json:"allow"`
+	Deny           []string `json:"deny"`
+	AllowCountries []string `json:"allow_countries,omitempty"`
+	DenyCountries  []string `json:"deny_countries,omitempty"`
+	RuntimeAllow   []string `json:"runtime_allow"`
+	RuntimeDeny    []string `json:"runtime_deny"`
+}
+
+// ListIPFilters 返回所有已注册的过滤器及其规则
+func ListIPFilters(ctx context.Context) ([]IPFilterInfo, error) {
+	ipFiltersMu.Lock()
+	filters := make([]*ipFilter, 0, len(ipFilters))
+	for _, f := range ipFilters {
+		filters = append(filters, f)
+	}
+	ipFiltersMu.Unlock()
+	sort.Slice(filters, func(i, j int) bool { return filters[i].policy.Name < filters[j].policy.Name })
+
+	infos := make([]IPFilterInfo, 0, len(filters))
+	for _, f := range filters {
+		info := IPFilterInfo{
+			Name:           f.policy.Name,
+			Allow:          f.policy.Allow,
+			Deny:           f.policy.Deny,
+			AllowCountries: f.policy.AllowCountries,
+			DenyCountries:  f.policy.DenyCountries,
+		}
+		var err error
+		if info.RuntimeAllow, err = f.store.SMembers(ctx, ipFilterKey(f.policy.Name, IPFilterAllow)); err != nil {
+			return nil, err
+		}
+		if info.RuntimeDeny, err = f.store.SMembers(ctx, ipFilterKey(f.policy.Name, IPFilterDeny)); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// AddIPFilterEntry 向过滤器的运行时规则添加网段, 所有实例在刷新周期内生效
+func AddIPFilterEntry(ctx context.Context, name, list, cidr string) error {
+	return updateIPFilter(ctx, name, list, cidr, cache.Store.SAdd)
+}
+
+// RemoveIPFilterEntry 从过滤器的运行时规则删除网段
+func RemoveIPFilterEntry(ctx context.Context, name, list, cidr string) error {
+	return updateIPFilter(ctx, name, list, cidr, cache.Store.SRem)
+}
+
+func updateIPFilter(ctx context.Context, name, list, cidr string,
+	update func(cache.Store, context.Context, string, ...string) error) error {
+	ipFiltersMu.Lock()
+	f, ok := ipFilters[name]
+	ipFiltersMu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownIPFilter, name)
+	}
+	if list != IPFilterAllow && list != IPFilterDeny {
+		return fmt.Errorf("%w: unknown list %s", ErrInvalidIPFilterEntry, list)
+	}
+	prefix, err := parsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIPFilterEntry, err)
+	}
+	if err := update(f.store, ctx, ipFilterKey(name, list), prefix.String()); err != nil {
+		return err
+	}
+	// 当前实例立即生效
+	f.runtime.Store(nil)
+	return nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
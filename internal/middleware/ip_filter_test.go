@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/pkg/cache"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	store := cache.NewMemoryStore("test:")
+	r.Use(RealIP(), IPFilter(IPFilterPolicy{
+		Name:  "test",
+		Allow: []string{"10.0.0.0/8"},
+		Store: store,
+	}))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	status := func(remote string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := status("10.1.2.3:1000"); got != http.StatusOK {
+		t.Fatalf("allowed network: status %d", got)
+	}
+	if got := status("203.0.113.1:1000"); got != http.StatusForbidden {
+		t.Fatalf("other network: status %d", got)
+	}
+	if got := status("not-an-address"); got != http.StatusForbidden {
+		t.Fatalf("unparsable address with an allow list: status %d", got)
+	}
+
+	ctx := context.Background()
+	if err := AddIPFilterEntry(ctx, "test", IPFilterDeny, "10.1.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddIPFilterEntry(ctx, "test", IPFilterAllow, "203.0.113.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("10.1.2.3:1000"); got != http.StatusForbidden {
+		t.Fatalf("runtime deny: status %d", got)
+	}
+	if got := status("203.0.113.1:1000"); got != http.StatusOK {
+		t.Fatalf("runtime allow: status %d", got)
+	}
+
+	// 清理缓存不能删除运行时规则
+	cache.SetDefault(store)
+	defer cache.SetDefault(nil)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	for _, prefix := range []string{"ipfilter:", ""} {
+		if err := ClearCacheByPrefix(c, prefix); !errors.Is(err, ErrCacheKeyOutOfScope) {
+			t.Errorf("ClearCacheByPrefix(%q) = %v, want ErrCacheKeyOutOfScope", prefix, err)
+		}
+	}
+	_ = ClearCacheByPrefix(c, responseCachePrefix())
+	if keys, _, _ := store.Scan(ctx, "", "", 10); len(keys) != 2 {
+		t.Errorf("runtime rules after clearing the cache: %v", keys)
+	}
+	ipFilters["test"].runtime.Store(nil)
+	if got := status("10.1.2.3:1000"); got != http.StatusForbidden {
+		t.Fatalf("runtime deny after clearing the cache: status %d", got)
+	}
+
+	if err := AddIPFilterEntry(ctx, "missing", IPFilterDeny, "10.0.0.1"); err == nil {
+		t.Fatal("expected an error for an unknown filter")
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"openapphub/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale 按 Accept-Language 选择响应消息的语言, 登录用户的语言偏好由 CurrentUser 覆盖
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setLocale(c, i18n.Negotiate("", c.GetHeader("Accept-Language")))
+		c.Next()
+	}
+}
+
+// setLocale 设置请求的语言, 后续通过 i18n.T(c, key) 翻译消息
+func setLocale(c *gin.Context, locale string) {
+	c.Set(i18n.ContextKey, locale)
+	c.Header("Content-Language", locale)
+}
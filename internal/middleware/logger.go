@@ -1,7 +1,18 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"openapphub/internal/util"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/redact"
+	"openapphub/pkg/secrets"
+	"openapphub/pkg/serializer"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	ginzap "github.com/gin-contrib/zap"
@@ -13,8 +24,150 @@ import (
 
 var zapLogger *zap.Logger
 
-// InitLogger 初始化zap日志
-func InitLogger() {
+// logFiles 日志文件的输出, 退出时关闭
+var logFiles []*lumberjack.Logger
+
+// 日志编码和输出
+const (
+	LogEncodingJSON    = "json"
+	LogEncodingConsole = "console"
+	LogOutputStdout    = "stdout"
+	LogOutputStderr    = "stderr"
+)
+
+// LogOptions 日志配置
+type LogOptions struct {
+	Level    string            // debug, info, warn, error, 可以在运行时修改
+	Encoding string            // json 或 console
+	Outputs  []string          // stdout、stderr 或文件路径, 文件按 Rotation 轮转
+	Rotation LogRotation       // 日志文件轮转
+	Sampling LogSampling       // 日志采样, 避免大量重复日志拖慢服务
+	Packages map[string]string // 按包名或 logger 名称覆盖日志级别, 例如 {"middleware": "debug"}, 可以在运行时修改
+}
+
+// LogRotation 日志文件轮转, 见 lumberjack.Logger
+type LogRotation struct {
+	MaxSize    int  // 单个文件的最大大小, 单位 MB
+	MaxBackups int  // 保留的旧文件数量, 0 表示不限制
+	MaxAge     int  // 旧文件保留的天数, 0 表示不限制
+	Compress   bool // 压缩旧文件
+}
+
+// LogSampling 每秒内同一级别、同一消息的日志只记录前 Initial 条, 之后每 Thereafter 条记录一条. Initial 为 0 时不采样
+type LogSampling struct {
+	Initial    int
+	Thereafter int
+}
+
+// DefaultLogOptions 默认输出 JSON 到标准输出和 ./logs/app.log
+func DefaultLogOptions() LogOptions {
+	return LogOptions{
+		Level:    "info",
+		Encoding: LogEncodingJSON,
+		Outputs:  []string{LogOutputStdout, "./logs/app.log"},
+		Rotation: LogRotation{MaxSize: 500, MaxBackups: 3, MaxAge: 28, Compress: true},
+	}
+}
+
+// ValidateLogOptions 校验日志配置, 返回所有错误
+func ValidateLogOptions(opts LogOptions) error {
+	var errs []error
+	if _, err := zapcore.ParseLevel(opts.Level); err != nil {
+		errs = append(errs, fmt.Errorf("level: must be one of debug, info, warn, error, got %q", opts.Level))
+	}
+	if opts.Encoding != LogEncodingJSON && opts.Encoding != LogEncodingConsole {
+		errs = append(errs, fmt.Errorf("encoding: must be json or console, got %q", opts.Encoding))
+	}
+	if len(opts.Outputs) == 0 {
+		errs = append(errs, fmt.Errorf("outputs: at least one output is required"))
+	}
+	if opts.Rotation.MaxSize < 0 || opts.Rotation.MaxBackups < 0 || opts.Rotation.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("rotation: values must not be negative"))
+	}
+	if opts.Sampling.Initial < 0 || opts.Sampling.Thereafter < 0 {
+		errs = append(errs, fmt.Errorf("sampling: values must not be negative"))
+	}
+	if _, err := parsePackageLevels(opts.Packages); err != nil {
+		errs = append(errs, fmt.Errorf("packages: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// logLevel 日志级别, 可以在运行时修改
+var logLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+// packageLevels 按包名或 logger 名称覆盖的日志级别, 可以在运行时修改
+var packageLevels atomic.Pointer[map[string]zapcore.Level]
+
+// SetLogLevel 修改日志级别, 例如 debug、info、warn、error
+func SetLogLevel(level string) error {
+	return logLevel.UnmarshalText([]byte(level))
+}
+
+// LogLevel 返回当前的日志级别
+func LogLevel() string {
+	return logLevel.String()
+}
+
+// SetPackageLogLevels 替换所有按包覆盖的日志级别, 为空时所有包使用 LogLevel
+func SetPackageLogLevels(levels map[string]string) error {
+	parsed, err := parsePackageLevels(levels)
+	if err != nil {
+		return err
+	}
+	packageLevels.Store(&parsed)
+	return nil
+}
+
+// SetPackageLogLevel 修改一个包的日志级别, level 为空时取消覆盖
+func SetPackageLogLevel(name, level string) error {
+	levels := PackageLogLevels()
+	if level == "" {
+		delete(levels, name)
+	} else {
+		levels[name] = level
+	}
+	return SetPackageLogLevels(levels)
+}
+
+// PackageLogLevels 返回按包覆盖的日志级别
+func PackageLogLevels() map[string]string {
+	levels := make(map[string]string)
+	if current := packageLevels.Load(); current != nil {
+		for name, level := range *current {
+			levels[name] = level.String()
+		}
+	}
+	return levels
+}
+
+func parsePackageLevels(levels map[string]string) (map[string]zapcore.Level, error) {
+	parsed := make(map[string]zapcore.Level, len(levels))
+	for name, level := range levels {
+		if name == "" {
+			return nil, fmt.Errorf("package name must not be empty")
+		}
+		l, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("%s: must be one of debug, info, warn, error, got %q", name, level)
+		}
+		parsed[name] = l
+	}
+	return parsed, nil
+}
+
+// InitLogger 按配置初始化zap日志
+func InitLogger(opts LogOptions) error {
+	if err := ValidateLogOptions(opts); err != nil {
+		return err
+	}
+	if err := SetLogLevel(opts.Level); err != nil {
+		return err
+	}
+	if err := SetPackageLogLevels(opts.Packages); err != nil {
+		return err
+	}
+
 	// 设置日志输出格式
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "time",
@@ -29,40 +182,248 @@ func InitLogger() {
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
+	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	if opts.Encoding == LogEncodingConsole {
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
 
-	// 设置日志轮转
-	writer := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   "./logs/app.log",
-		MaxSize:    500, // megabytes
-		MaxBackups: 3,
-		MaxAge:     28, // days
-		Compress:   true,
-	})
-
-	// 创建核心
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.NewMultiWriteSyncer(zapcore.AddSync(writer), zapcore.AddSync(os.Stdout)),
-		zap.InfoLevel,
-	)
+	// 文件输出按配置轮转
+	writers := make([]zapcore.WriteSyncer, 0, len(opts.Outputs))
+	files := make([]*lumberjack.Logger, 0, len(opts.Outputs))
+	for _, output := range opts.Outputs {
+		switch output {
+		case LogOutputStdout:
+			writers = append(writers, zapcore.Lock(os.Stdout))
+		case LogOutputStderr:
+			writers = append(writers, zapcore.Lock(os.Stderr))
+		default:
+			file := &lumberjack.Logger{
+				Filename:   output,
+				MaxSize:    opts.Rotation.MaxSize,
+				MaxBackups: opts.Rotation.MaxBackups,
+				MaxAge:     opts.Rotation.MaxAge,
+				Compress:   opts.Rotation.Compress,
+			}
+			files = append(files, file)
+			writers = append(writers, zapcore.AddSync(file))
+		}
+	}
 
-	// 创建logger
+	// 输出前隐藏配置中的密钥, 再按包的日志级别过滤
+	var core zapcore.Core = levelCore{secretCore{zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(writers...), zapcore.DebugLevel)}}
+	if opts.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, opts.Sampling.Initial, opts.Sampling.Thereafter)
+	}
 	zapLogger = zap.New(core, zap.AddCaller())
+	logFiles = files
 
-	zapLogger.Info("Logger initialized")
+	zapLogger.Info("Logger initialized", zap.String("level", opts.Level), zap.Strings("outputs", opts.Outputs))
+	return nil
 }
 
-// Logger 返回一个Gin的中间件，用于记录API请求
+// Logger 返回一个Gin的中间件，用于记录API请求, 日志中包含请求 ID 和 trace ID
 func Logger() gin.HandlerFunc {
-	return ginzap.Ginzap(zapLogger, time.RFC3339, true)
+	return ginzap.GinzapWithConfig(zapLogger.Named("access"), &ginzap.Config{
+		TimeFormat:   time.RFC3339,
+		UTC:          true,
+		DefaultLevel: zapcore.InfoLevel,
+		Context: func(c *gin.Context) []zapcore.Field {
+			return util.ContextFields(c)
+		},
+	})
 }
 
-// RecoveryWithZap 返回一个Gin的中间件，用于恢复panic并记录
+// RecoveryWithZap 返回一个Gin的中间件，用于恢复panic并记录, 响应中的 track_id 与日志中的请求 ID 相同
 func RecoveryWithZap() gin.HandlerFunc {
-	return ginzap.RecoveryWithZap(zapLogger, true)
+	return func(c *gin.Context) {
+		ginzap.CustomRecoveryWithZap(RequestLogger(c), true, func(c *gin.Context, _ interface{}) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, serializer.Track(c, serializer.Response{
+				Code: serializer.CodeInternalServerError,
+				Msg:  i18n.T(c, "Common.InternalError"),
+			}))
+		})(c)
+	}
+}
+
+// CloseLogger 写入缓冲的日志并关闭日志文件, 退出前最后调用
+func CloseLogger() error {
+	if zapLogger == nil {
+		return nil
+	}
+	// 标准输出是终端或管道时 fsync 会返回 EINVAL, 忽略 Sync 的错误
+	_ = zapLogger.Sync()
+	var errs []error
+	for _, file := range logFiles {
+		errs = append(errs, file.Close())
+	}
+	logFiles = nil
+	return errors.Join(errs...)
 }
 
 // GetZapLogger 返回zap logger实例，以便在其他地方使用
 func GetZapLogger() *zap.Logger {
 	return zapLogger
 }
+
+// levelCore 按 LogLevel 和按包覆盖的日志级别过滤日志.
+// 包名取自 logger 名称或调用方的包路径, 例如 "middleware" 匹配 openapphub/internal/middleware
+type levelCore struct {
+	zapcore.Core
+}
+
+// Enabled 只要全局或任一包的级别允许就先放行, 在 Write 中按包过滤
+func (c levelCore) Enabled(level zapcore.Level) bool {
+	if logLevel.Enabled(level) {
+		return true
+	}
+	if levels := packageLevels.Load(); levels != nil {
+		for _, l := range *levels {
+			if level >= l {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{c.Core.With(fields)}
+}
+
+func (c levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c levelCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if entry.Level < effectiveLevel(entry) {
+		return nil
+	}
+	return c.Core.Write(entry, fields)
+}
+
+// effectiveLevel 返回日志适用的级别. logger 名称的匹配优先于调用方包路径, 多个匹配时使用最长的
+func effectiveLevel(entry zapcore.Entry) zapcore.Level {
+	levels := packageLevels.Load()
+	if levels == nil || len(*levels) == 0 {
+		return logLevel.Level()
+	}
+	if level, ok := longestMatch(*levels, func(name string) bool {
+		return name == entry.LoggerName || strings.HasPrefix(entry.LoggerName, name+".")
+	}); ok {
+		return level
+	}
+	pkg := callerPackage(entry.Caller.Function)
+	if level, ok := longestMatch(*levels, func(name string) bool {
+		return pkg == name || strings.HasSuffix(pkg, "/"+name)
+	}); ok {
+		return level
+	}
+	return logLevel.Level()
+}
+
+func longestMatch(levels map[string]zapcore.Level, match func(name string) bool) (zapcore.Level, bool) {
+	matched, level := "", zapcore.InvalidLevel
+	for name, l := range levels {
+		if len(name) > len(matched) && match(name) {
+			matched, level = name, l
+		}
+	}
+	return level, matched != ""
+}
+
+// callerPackage 从函数全名中取出包路径, 例如 openapphub/internal/middleware.RateLimit.func1 -> openapphub/internal/middleware
+func callerPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// secretCore 把日志内容和字段中出现的密钥替换为 ******, 并按 redact 的规则隐藏敏感字段
+type secretCore struct {
+	zapcore.Core
+}
+
+func (c secretCore) With(fields []zapcore.Field) zapcore.Core {
+	return secretCore{c.Core.With(maskFields(fields))}
+}
+
+func (c secretCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c secretCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = secrets.Mask(entry.Message)
+	return c.Core.Write(entry, maskFields(fields))
+}
+
+func maskFields(fields []zapcore.Field) []zapcore.Field {
+	masked := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = secrets.Mask(field.String)
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				field = zap.String(field.Key, secrets.Mask(err.Error()))
+			}
+		case zapcore.StringerType:
+			if stringer, ok := field.Interface.(fmt.Stringer); ok {
+				field = zap.String(field.Key, secrets.Mask(stringer.String()))
+			}
+		case zapcore.ByteStringType:
+			if value, ok := field.Interface.([]byte); ok {
+				field = zap.String(field.Key, secrets.Mask(string(value)))
+			}
+		case zapcore.ReflectType, zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+			field = maskEncoded(field)
+		}
+		if field.Type == zapcore.StringType {
+			field.String = redactField(field.Key, field.String)
+		}
+		masked[i] = field
+	}
+	return masked
+}
+
+// maskEncoded 把 zap.Any、数组和对象字段编码为 JSON 后隐藏其中的密钥, 没有密钥时保留原字段
+func maskEncoded(field zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+	encoded, err := json.Marshal(enc.Fields[field.Key])
+	if err != nil {
+		return field
+	}
+	masked := secrets.Mask(string(encoded))
+	if masked == string(encoded) {
+		return field
+	}
+	if json.Valid([]byte(masked)) {
+		return zap.Reflect(field.Key, json.RawMessage(masked))
+	}
+	return zap.String(field.Key, masked)
+}
+
+// redactField 隐藏敏感字段的值. 访问日志的 query 和 panic 日志的 request 只隐藏其中的敏感参数和请求头
+func redactField(key, value string) string {
+	r := redact.Current()
+	switch {
+	case value == "":
+		return value
+	case key == "query":
+		return r.Query(value)
+	case key == "request":
+		return r.Request(value)
+	case r.Field(key):
+		return redact.Redacted
+	}
+	return value
+}
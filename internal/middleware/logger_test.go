@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"encoding/json"
+	"openapphub/pkg/secrets"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPackageLogLevels(t *testing.T) {
+	defer SetLogLevel(LogLevel())
+	defer SetPackageLogLevels(PackageLogLevels())
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(levelCore{observed}, zap.AddCaller())
+
+	if err := SetLogLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPackageLogLevels(map[string]string{"access": "info", "internal/middleware": "debug", "access.slow": "error"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPackageLogLevels(map[string]string{"access": "verbose"}); err == nil {
+		t.Error("SetPackageLogLevels accepted an invalid level")
+	}
+
+	// 调用方是本包, 匹配 internal/middleware
+	logger.Debug("caller package")
+	// logger 名称优先于调用方包路径匹配
+	logger.Named("access").Info("access info")
+	logger.Named("access").Named("slow").Info("slow info dropped")
+	logger.Named("access").Named("slow").Error("slow error")
+
+	want := []string{"caller package", "access info", "slow error"}
+	got := logs.All()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries %v, want %v", len(got), got, want)
+	}
+	for i, entry := range got {
+		if entry.Message != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, entry.Message, want[i])
+		}
+	}
+
+	if err := SetPackageLogLevel("internal/middleware", ""); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("global level applies again")
+	if logs.Len() != len(want) {
+		t.Errorf("override was not removed: %v", PackageLogLevels())
+	}
+}
+
+func TestLogRedaction(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(secretCore{observed})
+
+	logger.Info("/api/v1/user/login",
+		zap.String("query", "page=1&access_token=abc"),
+		zap.String("password", "hunter22"),
+		zap.String("request", "POST /api/v1/user/login HTTP/1.1\r\nAuthorization: Bearer abc\r\n"))
+
+	fields := logs.All()[0].ContextMap()
+	want := map[string]string{
+		"query":    "page=1&access_token=******",
+		"password": "******",
+		"request":  "POST /api/v1/user/login HTTP/1.1\r\nAuthorization: ******\r\n",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %q, want %q", key, fields[key], value)
+		}
+	}
+}
+
+func TestLogMasksStructuredFields(t *testing.T) {
+	const secret = "structured-secret-value"
+	secrets.Track(secret)
+	observed, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(secretCore{observed})
+
+	logger.Info("payload",
+		zap.Any("settings", map[string]string{"dsn": "user:" + secret + "@tcp(db)/app"}),
+		zap.Strings("args", []string{"--key", secret}),
+		zap.ByteString("body", []byte("key="+secret)))
+
+	for key, value := range logs.All()[0].ContextMap() {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(encoded), secret) || !strings.Contains(string(encoded), secrets.Redacted) {
+			t.Errorf("%s = %s, want the secret masked", key, encoded)
+		}
+	}
+}
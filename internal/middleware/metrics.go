@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"openapphub/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute 未匹配任何路由的请求使用的 route 标签, 避免扫描请求产生大量标签值
+const unmatchedRoute = "unmatched"
+
+// Metrics 按路由模板和状态码统计请求数和处理时间, 需要放在限流等可能中断请求的中间件之前
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
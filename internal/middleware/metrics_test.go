@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"openapphub/internal/metrics"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	requests := func(route, status string) float64 {
+		return testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, route, status))
+	}
+	before, unmatched := requests("/users/:id", "200"), requests(unmatchedRoute, "404")
+
+	for _, path := range []string{"/users/1", "/users/2", "/scan/1", "/scan/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// 按路由模板统计, 未匹配的路径合并为一个标签值
+	if got := requests("/users/:id", "200") - before; got != 2 {
+		t.Errorf("route requests = %v, want 2", got)
+	}
+	if got := requests(unmatchedRoute, "404") - unmatched; got != 2 {
+		t.Errorf("unmatched requests = %v, want 2", got)
+	}
+}
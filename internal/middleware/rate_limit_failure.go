@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ulule/limiter/v3"
+	"go.uber.org/zap"
+)
+
+// Failure modes deciding what happens to a request when the limiter store is unavailable
+const (
+	FailOpen   = "open"   // let the request through without rate limiting
+	FailClosed = "closed" // reject the request with 503
+	FailLocal  = "local"  // count in a per-instance memory store; limits become approximate
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// errBreakerOpen is returned while the breaker short-circuits calls to the store
+var errBreakerOpen = errors.New("rate limit store circuit breaker is open")
+
+// RateLimitFailureConfig controls how rate limiting degrades when its store fails
+type RateLimitFailureConfig struct {
+	Mode             string        // Default failure mode for policies that do not set one
+	BreakerThreshold int           // Consecutive store errors before the breaker opens
+	BreakerCooldown  time.Duration // Time the breaker stays open before a trial request is let through
+}
+
+var rateLimitFailure = RateLimitFailureConfig{
+	Mode:             FailLocal,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// ConfigureRateLimitFailure sets the failure configuration used by rate limiters created afterwards
+func ConfigureRateLimitFailure(conf RateLimitFailureConfig) error {
+	if err := validateFailureMode(conf.Mode); err != nil {
+		return err
+	}
+	if conf.BreakerThreshold <= 0 {
+		return fmt.Errorf("rate limit breaker threshold must be positive")
+	}
+	if conf.BreakerCooldown <= 0 {
+		return fmt.Errorf("rate limit breaker cooldown must be positive")
+	}
+	rateLimitFailure = conf
+	return nil
+}
+
+func validateFailureMode(mode string) error {
+	switch mode {
+	case FailOpen, FailClosed, FailLocal:
+		return nil
+	}
+	return fmt.Errorf("unknown rate limit failure mode: %s", mode)
+}
+
+// RateLimitStats describes the health of a policy's limiter store on this instance
+type RateLimitStats struct {
+	Breaker     string `json:"breaker"`
+	Failures    int64  `json:"failures"`    // Store errors, including calls rejected by the open breaker
+	Transitions int64  `json:"transitions"` // Breaker state changes
+	FailOpen    int64  `json:"fail_open"`   // Requests let through without rate limiting
+	FailClosed  int64  `json:"fail_closed"` // Requests rejected because the store was unavailable
+	Fallback    int64  `json:"fallback"`    // Requests counted in the local memory store
+}
+
+var rateLimitStats = struct {
+	sync.Mutex
+	policies map[string]*RateLimitStats
+}{policies: make(map[string]*RateLimitStats)}
+
+func recordRateLimitStats(policy string, update func(s *RateLimitStats)) {
+	rateLimitStats.Lock()
+	defer rateLimitStats.Unlock()
+
+	stats, ok := rateLimitStats.policies[policy]
+	if !ok {
+		stats = &RateLimitStats{Breaker: BreakerClosed}
+		rateLimitStats.policies[policy] = stats
+	}
+	update(stats)
+}
+
+// RateLimitStatsSnapshot returns the limiter store health of every policy
+func RateLimitStatsSnapshot() map[string]RateLimitStats {
+	rateLimitStats.Lock()
+	defer rateLimitStats.Unlock()
+
+	snapshot := make(map[string]RateLimitStats, len(rateLimitStats.policies))
+	for policy, stats := range rateLimitStats.policies {
+		snapshot[policy] = *stats
+	}
+	return snapshot
+}
+
+// breakerStore wraps a limiter store with a circuit breaker.
+// After BreakerThreshold consecutive errors calls fail fast for BreakerCooldown,
+// then a single trial call decides whether the breaker closes again.
+// If the store could not be created at startup, connect is retried on every trial.
+type breakerStore struct {
+	policy    string
+	connect   func() (limiter.Store, error)
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	store    limiter.Store
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func newBreakerStore(policy string, connect func() (limiter.Store, error)) *breakerStore {
+	b := &breakerStore{
+		policy:    policy,
+		connect:   connect,
+		threshold: rateLimitFailure.BreakerThreshold,
+		cooldown:  rateLimitFailure.BreakerCooldown,
+		state:     BreakerClosed,
+	}
+	recordRateLimitStats(policy, func(s *RateLimitStats) {})
+
+	store, err := connect()
+	if err != nil {
+		GetZapLogger().Error("Rate limit store unavailable", zap.String("policy", policy), zap.Error(err))
+		recordRateLimitStats(policy, func(s *RateLimitStats) { s.Failures++ })
+		b.setState(BreakerOpen)
+		b.openedAt = time.Now()
+		return b
+	}
+	b.store = store
+	return b
+}
+
+// acquire returns the store to call, or errBreakerOpen while the breaker is open
+func (b *breakerStore) acquire() (limiter.Store, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return b.store, nil
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return nil, errBreakerOpen
+		}
+		b.setState(BreakerHalfOpen)
+	}
+
+	// Half-open: only one trial call at a time
+	if b.trial {
+		return nil, errBreakerOpen
+	}
+	b.trial = true
+	if b.store == nil {
+		store, err := b.connect()
+		if err != nil {
+			b.trial = false
+			b.trip(err)
+			return nil, err
+		}
+		b.store = store
+	}
+	return b.store, nil
+}
+
+// retryAfter is how long clients should wait before the next trial call can reach the store:
+// the rest of the cooldown while the breaker is open, otherwise one second
+func (b *breakerStore) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if remaining := b.cooldown - time.Since(b.openedAt); remaining > time.Second {
+			return remaining
+		}
+	}
+	return time.Second
+}
+
+// done records the outcome of a call returned by acquire
+func (b *breakerStore) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+	b.trip(err)
+}
+
+// trip counts a failure and opens the breaker when needed; b.mu must be held
+func (b *breakerStore) trip(err error) {
+	recordRateLimitStats(b.policy, func(s *RateLimitStats) { s.Failures++ })
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		if b.state != BreakerOpen {
+			GetZapLogger().Warn("Rate limit store failing", zap.String("policy", b.policy), zap.Error(err))
+			b.setState(BreakerOpen)
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+// setState changes the breaker state and logs the transition; b.mu must be held
+func (b *breakerStore) setState(state string) {
+	from := b.state
+	b.state = state
+	recordRateLimitStats(b.policy, func(s *RateLimitStats) {
+		s.Breaker = state
+		s.Transitions++
+	})
+	GetZapLogger().Info("Rate limit circuit breaker transition",
+		zap.String("policy", b.policy),
+		zap.String("from", from),
+		zap.String("to", state))
+}
+
+func (b *breakerStore) call(fn func(store limiter.Store) (limiter.Context, error)) (limiter.Context, error) {
+	store, err := b.acquire()
+	if err != nil {
+		if errors.Is(err, errBreakerOpen) {
+			recordRateLimitStats(b.policy, func(s *RateLimitStats) { s.Failures++ })
+		}
+		return limiter.Context{}, err
+	}
+	ctx, err := fn(store)
+	b.done(err)
+	return ctx, err
+}
+
+// Get implements limiter.Store
+func (b *breakerStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return b.call(func(store limiter.Store) (limiter.Context, error) { return store.Get(ctx, key, rate) })
+}
+
+// Peek implements limiter.Store
+func (b *breakerStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return b.call(func(store limiter.Store) (limiter.Context, error) { return store.Peek(ctx, key, rate) })
+}
+
+// Reset implements limiter.Store
+func (b *breakerStore) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return b.call(func(store limiter.Store) (limiter.Context, error) { return store.Reset(ctx, key, rate) })
+}
+
+// Increment implements limiter.Store
+func (b *breakerStore) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	return b.call(func(store limiter.Store) (limiter.Context, error) { return store.Increment(ctx, key, count, rate) })
+}
@@ -2,14 +2,21 @@ package middleware
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"openapphub/internal/metrics"
 	"openapphub/internal/model"
 	"openapphub/pkg/cache"
+	"openapphub/pkg/i18n"
 	"openapphub/pkg/serializer"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ulule/limiter/v3"
+	smemory "github.com/ulule/limiter/v3/drivers/store/memory"
 	sredis "github.com/ulule/limiter/v3/drivers/store/redis"
 	"go.uber.org/zap"
 )
@@ -18,25 +25,200 @@ import (
 type RateLimiterConfig struct {
 	RateString  string // Rate limit string (e.g., "100-H" for 100 requests per hour)
 	LimitByUser bool   // If true, limit by user ID; if false, limit by IP
+	// Store is the cache backend holding the counters; nil uses cache.Default()
+	Store cache.Store
+}
+
+// RateLimitPolicy is a named set of limits attached to a route group.
+// All limits are evaluated together, so a policy can combine a burst limit
+// with a sustained one (e.g. "10-S" and "1000-H").
+type RateLimitPolicy struct {
+	Name        string
+	Limits      []string // Rate limit strings applied to anonymous users and tiers without an override
+	LimitByUser bool     // If true, limit logged-in users by user ID; anonymous requests are limited by IP
+	// Tiers overrides Limits for users of the given tier (model.TierFree, model.TierPro, ...).
+	// An empty list means the tier is not rate limited.
+	Tiers map[string][]string
+	// Store is the cache backend holding the counters; nil uses cache.Default()
+	Store cache.Store
+	// FailureMode decides what happens when the store fails (FailOpen, FailClosed, FailLocal);
+	// empty uses the mode from ConfigureRateLimitFailure
+	FailureMode string
+}
+
+// rateLimit is a single parsed limit of a policy
+type rateLimit struct {
+	formatted string
+	instance  *limiter.Limiter
+	fallback  *limiter.Limiter // Counts in local memory while the store is unavailable (FailLocal only)
+}
+
+// policyLimits are the parsed limits of a policy, swapped as a whole when the policy is reconfigured
+type policyLimits struct {
+	defaults []rateLimit
+	tiers    map[string][]rateLimit
+}
+
+// RateLimitOverride replaces the limits of a named policy at runtime.
+// Nil Tiers keeps the tiers defined in code.
+type RateLimitOverride struct {
+	Limits []string
+	Tiers  map[string][]string
+}
+
+// policyLimiter is a policy attached to a route group together with its stores
+type policyLimiter struct {
+	policy   RateLimitPolicy
+	store    limiter.Store
+	fallback limiter.Store
+	limits   atomic.Pointer[policyLimits]
+}
+
+// rateLimiters keeps every policy by name so overrides can be applied to running middlewares
+var rateLimiters = struct {
+	sync.Mutex
+	policies  map[string][]*policyLimiter
+	overrides map[string]RateLimitOverride
+}{policies: make(map[string][]*policyLimiter)}
+
+// ValidateRateLimits checks that every rate string can be parsed
+func ValidateRateLimits(rateStrings []string) error {
+	for _, rateString := range rateStrings {
+		if _, err := limiter.NewRateFromFormatted(rateString); err != nil {
+			return fmt.Errorf("invalid rate limit %q: %w", rateString, err)
+		}
+	}
+	return nil
+}
+
+// ConfigureRateLimits applies overrides to running and future policies.
+// Policies missing from overrides go back to the limits defined in code.
+// Nothing changes if any override is invalid.
+func ConfigureRateLimits(overrides map[string]RateLimitOverride) error {
+	for name, override := range overrides {
+		if err := ValidateRateLimits(override.Limits); err != nil {
+			return fmt.Errorf("rate limit policy %s: %w", name, err)
+		}
+		for tier, limits := range override.Tiers {
+			if err := ValidateRateLimits(limits); err != nil {
+				return fmt.Errorf("rate limit policy %s tier %s: %w", name, tier, err)
+			}
+		}
+	}
+
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+	rateLimiters.overrides = overrides
+	for name, limiters := range rateLimiters.policies {
+		override, ok := overrides[name]
+		for _, l := range limiters {
+			if ok {
+				l.apply(override)
+			} else {
+				l.apply(RateLimitOverride{Limits: l.policy.Limits, Tiers: l.policy.Tiers})
+			}
+		}
+	}
+	return nil
+}
+
+// apply parses the limits and swaps them in; the limits must already be validated.
+// An override without limits or tiers keeps the ones defined in code, so it never turns limiting off.
+func (l *policyLimiter) apply(override RateLimitOverride) {
+	defaultLimits := override.Limits
+	if len(defaultLimits) == 0 {
+		defaultLimits = l.policy.Limits
+	}
+	tierLimits := override.Tiers
+	if tierLimits == nil {
+		tierLimits = l.policy.Tiers
+	}
+	limits := &policyLimits{
+		defaults: setupRateLimits(l.store, l.fallback, defaultLimits),
+		tiers:    make(map[string][]rateLimit, len(tierLimits)),
+	}
+	for tier, rateStrings := range tierLimits {
+		limits.tiers[tier] = setupRateLimits(l.store, l.fallback, rateStrings)
+	}
+	l.limits.Store(limits)
+}
+
+// rateLimitLegacyHeaders enables the X-RateLimit-* headers next to the IETF RateLimit headers.
+// Config reloads write it while requests read it, so it is atomic
+var rateLimitLegacyHeaders atomic.Bool
+
+func init() {
+	rateLimitLegacyHeaders.Store(true)
+}
+
+// SetRateLimitLegacyHeaders turns the legacy X-RateLimit-* headers on or off
+func SetRateLimitLegacyHeaders(enabled bool) {
+	rateLimitLegacyHeaders.Store(enabled)
 }
 
 // RateLimiter returns a Gin middleware for rate limiting.
 // It can limit by IP or user ID, depending on the configuration.
 func RateLimiter(config RateLimiterConfig) gin.HandlerFunc {
+	return RateLimit(RateLimitPolicy{
+		Name:        "default",
+		Limits:      []string{config.RateString},
+		LimitByUser: config.LimitByUser,
+		Store:       config.Store,
+	})
+}
+
+// RateLimit returns a Gin middleware enforcing the given policy.
+func RateLimit(policy RateLimitPolicy) gin.HandlerFunc {
 	// Initialize rate limiter components
-	rate, store, instance := setupRateLimiter(config.RateString)
+	backend := policy.Store
+	if backend == nil {
+		backend = cache.Default()
+	}
+	mode := policy.FailureMode
+	if mode == "" {
+		mode = rateLimitFailure.Mode
+	}
+	if err := validateFailureMode(mode); err != nil {
+		panic(err)
+	}
+	l := &policyLimiter{policy: policy, store: newLimiterStore(backend, policy.Name)}
+	if mode == FailLocal {
+		l.fallback = smemory.NewStoreWithOptions(limiterStoreOptions(backend, policy.Name))
+	}
+
+	// Limits defined in code must be valid; overrides were validated when configured
+	if err := ValidateRateLimits(policy.Limits); err != nil {
+		panic(err)
+	}
+	for _, limits := range policy.Tiers {
+		if err := ValidateRateLimits(limits); err != nil {
+			panic(err)
+		}
+	}
+	rateLimiters.Lock()
+	if override, ok := rateLimiters.overrides[policy.Name]; ok {
+		l.apply(override)
+	} else {
+		l.apply(RateLimitOverride{Limits: policy.Limits, Tiers: policy.Tiers})
+	}
+	rateLimiters.policies[policy.Name] = append(rateLimiters.policies[policy.Name], l)
+	rateLimiters.Unlock()
 
 	return func(c *gin.Context) {
-		// Determine the identifier for rate limiting
-		key := getIdentifier(c, config.LimitByUser)
+		// Determine the identifier and the limits for this request
+		key := getIdentifier(c, policy.LimitByUser)
+		current := l.limits.Load()
+		limits := current.defaults
+		if user := rateLimitUser(c); user != nil {
+			if tierLimits, ok := current.tiers[user.Tier]; ok {
+				limits = tierLimits
+			}
+		}
 
 		// Apply rate limiting
-		var _ limiter.Store = store
-		var _ limiter.Rate = rate
-		err := applyRateLimit(c, instance, key)
+		err := applyRateLimit(c, policy.Name, mode, limits, key)
 		if err != nil {
-			// Use the global logger to log the error
-			GetZapLogger().Error("Rate limit error", zap.Error(err))
+			RequestLogger(c).Error("Rate limit error", zap.String("policy", policy.Name), zap.Error(err))
 		}
 
 		// If the context was aborted (due to rate limiting), don't continue
@@ -49,25 +231,55 @@ func RateLimiter(config RateLimiterConfig) gin.HandlerFunc {
 	}
 }
 
-// setupRateLimiter initializes the rate limiter components
-func setupRateLimiter(rateString string) (limiter.Rate, limiter.Store, *limiter.Limiter) {
-	// Parse the rate limit string
-	rate, err := limiter.NewRateFromFormatted(rateString)
-	if err != nil {
-		panic(err)
+// setupRateLimits parses the rate strings of a policy
+func setupRateLimits(store, fallback limiter.Store, rateStrings []string) []rateLimit {
+	limits := make([]rateLimit, 0, len(rateStrings))
+	for _, rateString := range rateStrings {
+		// Parse the rate limit string
+		rate, err := limiter.NewRateFromFormatted(rateString)
+		if err != nil {
+			panic(err)
+		}
+		limit := rateLimit{
+			formatted: rateString,
+			instance:  limiter.New(store, rate),
+		}
+		if fallback != nil {
+			limit.fallback = limiter.New(fallback, rate)
+		}
+		limits = append(limits, limit)
 	}
+	return limits
+}
 
-	// Create a Redis store for rate limiting
-	store, err := sredis.NewStoreWithOptions(cache.RedisClient, limiter.StoreOptions{
-		Prefix: "limiter_", // Prefix for Redis keys
-	})
-	if err != nil {
-		panic(err)
+// newLimiterStore picks the limiter store matching the cache backend.
+// Redis backends share counters across instances and sit behind a circuit breaker;
+// anything else counts in memory.
+func newLimiterStore(backend cache.Store, policyName string) limiter.Store {
+	options := limiterStoreOptions(backend, policyName)
+	if rs, ok := backend.(*cache.RedisStore); ok {
+		return newBreakerStore(policyName, func() (limiter.Store, error) {
+			return sredis.NewStoreWithOptions(rs.Client(), options)
+		})
 	}
+	return smemory.NewStoreWithOptions(options)
+}
 
-	// Create a new rate limiter instance
-	instance := limiter.New(store, rate)
-	return rate, store, instance
+func limiterStoreOptions(backend cache.Store, policyName string) limiter.StoreOptions {
+	return limiter.StoreOptions{
+		Prefix:          backend.Namespace() + "limiter_" + policyName, // Prefix for limiter keys
+		CleanUpInterval: limiter.DefaultCleanUpInterval,
+	}
+}
+
+// rateLimitUser returns the logged-in user, if any
+func rateLimitUser(c *gin.Context) *model.User {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	u, _ := user.(*model.User)
+	return u
 }
 
 // getIdentifier returns the identifier for rate limiting.
@@ -75,47 +287,127 @@ func setupRateLimiter(rateString string) (limiter.Rate, limiter.Store, *limiter.
 // Otherwise, it uses the client's IP address.
 func getIdentifier(c *gin.Context, limitByUser bool) string {
 	if limitByUser {
-		// Get the user from the context
-		user, exists := c.Get("user")
-		if exists {
-			// Check if the user is of type *model.User
-			if u, ok := user.(*model.User); ok {
-				return fmt.Sprintf("user:%d", u.ID)
-			}
+		if u := rateLimitUser(c); u != nil {
+			return fmt.Sprintf("user:%d", u.ID)
 		}
 	}
 	// Use the client's IP address as the identifier
-	return "ip:" + c.ClientIP()
-}
-
-// applyRateLimit applies the rate limit and sets appropriate headers
-func applyRateLimit(c *gin.Context, instance *limiter.Limiter, key string) error {
-	// Get the rate limit context
-	context, err := instance.Get(c, key)
-	if err != nil {
-		response := serializer.Err(serializer.CodeInternalServerError, "Failed to get rate limit info", err)
-		c.JSON(http.StatusInternalServerError, response)
-		GetZapLogger().Error("Failed to get rate limit info", zap.Any("response", response))
-		c.Abort()
-		return err
-	}
-
-	// Check if the rate limit has been exceeded
-	if context.Reached {
-		response := serializer.Response{
-			Code: serializer.CodeRateLimitExceeded,
-			Msg:  "Rate limit exceeded",
+	return "ip:" + ClientIP(c)
+}
+
+// applyRateLimit evaluates every limit of the policy and sets appropriate headers.
+// RateLimit-Policy lists every limit; RateLimit and the legacy X- headers describe the most restrictive one.
+// Store errors are handled according to mode and are not returned.
+func applyRateLimit(c *gin.Context, policyName, mode string, limits []rateLimit, key string) error {
+	for _, limit := range limits {
+		c.Writer.Header().Add("RateLimit-Policy", rateLimitPolicyItem(policyName, limit))
+	}
+
+	var tightest *limiter.Context
+	var tightestLimit rateLimit
+	degraded := false
+	for _, limit := range limits {
+		// Get the rate limit context; each limit keeps its own counter
+		context, err := limit.instance.Get(c, key+":"+limit.formatted)
+		if err != nil {
+			if !degraded && mode != FailClosed {
+				recordRateLimitStats(policyName, func(s *RateLimitStats) {
+					if mode == FailLocal {
+						s.Fallback++
+					} else {
+						s.FailOpen++
+					}
+				})
+			}
+			degraded = true
+			switch mode {
+			case FailClosed:
+				recordRateLimitStats(policyName, func(s *RateLimitStats) { s.FailClosed++ })
+				metrics.RateLimitRejections.WithLabelValues(policyName, "unavailable").Inc()
+				// The store is unavailable, not broken: tell clients when the breaker will try it again
+				retryAfter := time.Second
+				if breaker, ok := limit.instance.Store.(*breakerStore); ok {
+					retryAfter = breaker.retryAfter()
+				}
+				c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+				response := serializer.Err(serializer.CodeServerBusy, i18n.T(c, "RateLimit.Unavailable"), err)
+				c.JSON(http.StatusServiceUnavailable, serializer.Track(c, response))
+				c.Abort()
+				return nil
+			case FailLocal:
+				context, err = limit.fallback.Get(c, key+":"+limit.formatted)
+				if err != nil {
+					return err
+				}
+			default:
+				// Fail open: skip this limit
+				continue
+			}
+		}
+
+		// Check if the rate limit has been exceeded
+		if context.Reached {
+			retryAfter := max(resetSeconds(context.Reset), 1)
+			c.Writer.Header().Add("RateLimit", rateLimitItem(policyName, limit, context))
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			response := serializer.Response{
+				Code: serializer.CodeRateLimitExceeded,
+				Msg:  i18n.T(c, "RateLimit.Exceeded", i18n.Params{"seconds": retryAfter}),
+				Data: serializer.RateLimitInfo{
+					Policy:     policyName,
+					Limit:      limit.formatted,
+					Reset:      time.Unix(context.Reset, 0).UTC(),
+					RetryAfter: retryAfter,
+				},
+			}
+			c.JSON(http.StatusTooManyRequests, response)
+			metrics.RateLimitRejections.WithLabelValues(policyName, "exceeded").Inc()
+			RequestLogger(c).Warn("Rate limit exceeded",
+				zap.String("policy", policyName),
+				zap.String("limit", limit.formatted),
+				zap.String("key", key))
+			c.Abort()
+			return nil
+		}
+
+		if tightest == nil || context.Remaining < tightest.Remaining {
+			tightest = &context
+			tightestLimit = limit
 		}
-		c.JSON(http.StatusTooManyRequests, response)
-		GetZapLogger().Warn("Rate limit exceeded", zap.String("key", key))
-		c.Abort()
+	}
+
+	if tightest == nil {
 		return nil
 	}
 
 	// Set the rate limit headers
-	c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", context.Limit))
-	c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", context.Remaining))
-	c.Header("X-RateLimit-Reset", time.Unix(context.Reset, 0).Format(time.RFC3339))
+	c.Writer.Header().Add("RateLimit", rateLimitItem(policyName, tightestLimit, *tightest))
+	if rateLimitLegacyHeaders.Load() {
+		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", tightest.Limit))
+		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", tightest.Remaining))
+		c.Header("X-RateLimit-Reset", time.Unix(tightest.Reset, 0).Format(time.RFC3339))
+	}
 
 	return nil
 }
+
+// rateLimitName names a single limit in the IETF headers, e.g. "api-1000-H"
+func rateLimitName(policyName string, limit rateLimit) string {
+	return strconv.Quote(policyName + "-" + limit.formatted)
+}
+
+// rateLimitPolicyItem formats a RateLimit-Policy list item: quota and window in seconds
+func rateLimitPolicyItem(policyName string, limit rateLimit) string {
+	return fmt.Sprintf("%s;q=%d;w=%d", rateLimitName(policyName, limit),
+		limit.instance.Rate.Limit, int64(limit.instance.Rate.Period/time.Second))
+}
+
+// rateLimitItem formats a RateLimit list item: remaining quota and seconds until reset
+func rateLimitItem(policyName string, limit rateLimit, context limiter.Context) string {
+	return fmt.Sprintf("%s;r=%d;t=%d", rateLimitName(policyName, limit), context.Remaining, resetSeconds(context.Reset))
+}
+
+// resetSeconds returns the seconds left until the given unix time
+func resetSeconds(reset int64) int64 {
+	return max(reset-time.Now().Unix(), 0)
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/internal/model"
+	"openapphub/pkg/cache"
+	"openapphub/pkg/serializer"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ulule/limiter/v3"
+	smemory "github.com/ulule/limiter/v3/drivers/store/memory"
+)
+
+func TestRateLimitTiers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if tier := c.GetHeader("X-Tier"); tier != "" {
+			c.Set("user", &model.User{Tier: tier})
+		}
+		c.Next()
+	})
+	r.Use(RateLimit(RateLimitPolicy{
+		Name:        "test",
+		Limits:      []string{"2-M", "100-H"},
+		LimitByUser: true,
+		Tiers:       map[string][]string{model.TierPro: {"3-M"}, model.TierInternal: {}},
+		Store:       cache.NewMemoryStore("test:"),
+	}))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	allowed := func(tier string, n int) int {
+		ok := 0
+		for i := 0; i < n; i++ {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Tier", tier)
+			r.ServeHTTP(w, req)
+			if w.Code == http.StatusOK {
+				ok++
+			}
+		}
+		return ok
+	}
+
+	if got := allowed("", 5); got != 2 {
+		t.Fatalf("anonymous: allowed %d requests, want 2", got)
+	}
+	if got := allowed(model.TierPro, 5); got != 3 {
+		t.Fatalf("pro: allowed %d requests, want 3", got)
+	}
+	if got := allowed(model.TierInternal, 5); got != 5 {
+		t.Fatalf("internal: allowed %d requests, want 5", got)
+	}
+}
+
+type failingLimiterStore struct{ calls int }
+
+func (s *failingLimiterStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	s.calls++
+	return limiter.Context{}, errors.New("connection refused")
+}
+
+func (s *failingLimiterStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return s.Get(ctx, key, rate)
+}
+
+func (s *failingLimiterStore) Reset(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return s.Get(ctx, key, rate)
+}
+
+func (s *failingLimiterStore) Increment(ctx context.Context, key string, count int64, rate limiter.Rate) (limiter.Context, error) {
+	return s.Get(ctx, key, rate)
+}
+
+func TestRateLimitFailureModes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	failing := &failingLimiterStore{}
+	breaker := newBreakerStore("failure-test", func() (limiter.Store, error) { return failing, nil })
+	fallback := smemory.NewStore()
+	limits := setupRateLimits(breaker, fallback, []string{"2-M"})
+
+	status := func(mode string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if err := applyRateLimit(c, "failure-test", mode, limits, "ip:"+mode); err != nil {
+			t.Fatal(err)
+		}
+		if c.IsAborted() {
+			return w.Code
+		}
+		return http.StatusOK
+	}
+
+	if got := status(FailClosed); got != http.StatusServiceUnavailable {
+		t.Fatalf("fail closed: status %d, want 503", got)
+	}
+	if got := status(FailOpen); got != http.StatusOK {
+		t.Fatalf("fail open: status %d, want 200", got)
+	}
+	codes := []int{status(FailLocal), status(FailLocal), status(FailLocal)}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("fallback: statuses %v, want 200 200 429", codes)
+	}
+
+	// The breaker opens after the threshold and stops calling the store
+	if stats := RateLimitStatsSnapshot()["failure-test"]; stats.Breaker != BreakerOpen {
+		t.Fatalf("breaker state %s, want open", stats.Breaker)
+	}
+
+	// Fail closed answers 503 as back-pressure until the breaker tries the store again
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	_ = applyRateLimit(c, "failure-test", FailClosed, limits, "ip:retry")
+	if retry, _ := strconv.Atoi(w.Header().Get("Retry-After")); retry < 2 || retry > 30 {
+		t.Errorf("Retry-After = %q, want the remaining breaker cooldown", w.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(w.Body.String(), strconv.Itoa(serializer.CodeServerBusy)) {
+		t.Errorf("body = %s, want code %d", w.Body.String(), serializer.CodeServerBusy)
+	}
+	calls := failing.calls
+	status(FailOpen)
+	if failing.calls != calls {
+		t.Fatal("open breaker still called the store")
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(RateLimitPolicy{
+		Name:   "headers",
+		Limits: []string{"1-M", "100-H"},
+		Store:  cache.NewMemoryStore("test:"),
+	}))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := w.Header().Values("RateLimit-Policy"); len(got) != 2 || got[0] != `"headers-1-M";q=1;w=60` {
+		t.Fatalf("RateLimit-Policy = %q", got)
+	}
+	if got := w.Header().Get("RateLimit"); !strings.HasPrefix(got, `"headers-1-M";r=0;t=`) {
+		t.Fatalf("RateLimit = %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Fatalf("Retry-After = %q", w.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(w.Body.String(), `"policy":"headers"`) {
+		t.Fatalf("body does not describe the limit: %s", w.Body.String())
+	}
+}
+
+func TestConfigureRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(RateLimitPolicy{
+		Name:   "reloadable",
+		Limits: []string{"1-M"},
+		Store:  cache.NewMemoryStore("reload:"),
+	}))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	defer ConfigureRateLimits(nil)
+
+	status := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+	if status() != http.StatusOK || status() != http.StatusTooManyRequests {
+		t.Fatal("expected the code limit of 1 request per minute")
+	}
+
+	err := ConfigureRateLimits(map[string]RateLimitOverride{"reloadable": {Limits: []string{"5-X"}}})
+	if err == nil {
+		t.Fatal("expected invalid rate limit error")
+	}
+	if status() != http.StatusTooManyRequests {
+		t.Fatal("invalid override should not be applied")
+	}
+
+	if err := ConfigureRateLimits(map[string]RateLimitOverride{"reloadable": {Limits: []string{"5-M"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if status() != http.StatusOK {
+		t.Fatal("override should raise the limit")
+	}
+	if err := ConfigureRateLimits(nil); err != nil {
+		t.Fatal(err)
+	}
+	if status() != http.StatusTooManyRequests {
+		t.Fatal("removing the override should restore the code limit")
+	}
+
+	if err := ConfigureRateLimits(map[string]RateLimitOverride{"reloadable": {Tiers: map[string][]string{"pro": {"5-M"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if status() != http.StatusTooManyRequests {
+		t.Fatal("a tiers-only override should keep the code limit")
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"openapphub/internal/util"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 客户端或上游传入的请求 ID 的最大长度
+const maxRequestIDLength = 128
+
+// RequestID 为每个请求分配请求 ID: 沿用请求头 X-Request-ID 中合法的值, 否则生成新的 ID.
+// 请求 ID 写入响应头, 并出现在这个请求的所有日志中
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(util.RequestIDKey, id)
+		c.Request = c.Request.WithContext(util.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID 只接受字母、数字和 -_.:, 避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		b := id[i]
+		if !('a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b == '-' || b == '_' || b == '.' || b == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成 32 位十六进制的随机 ID
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RequestLogger 返回带有请求 ID 和 trace ID 的 zap logger, 处理请求的代码应使用它代替 GetZapLogger
+func RequestLogger(ctx context.Context) *zap.Logger {
+	if fields := util.ContextFields(ctx); len(fields) > 0 {
+		return zapLogger.With(fields...)
+	}
+	return zapLogger
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"openapphub/internal/util"
+	"openapphub/pkg/serializer"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	var fromContext, trackID string
+	r.GET("/", func(c *gin.Context) {
+		fromContext = util.RequestID(c.Request.Context())
+		trackID = serializer.Track(c, serializer.Response{Code: serializer.CodeInternalServerError}).TrackID
+	})
+
+	tests := []struct {
+		name, header string
+		reuse        bool
+	}{
+		{"generated", "", false},
+		{"accepted from upstream", "edge-1:7f3a.42_b", true},
+		{"rejects unsafe characters", "abc\nfake log line", false},
+		{"rejects overlong ids", string(make([]byte, maxRequestIDLength+1)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tt.reuse && id != tt.header {
+				t.Fatalf("request id = %q, want %q", id, tt.header)
+			}
+			if !tt.reuse && (id == tt.header || len(id) != 32) {
+				t.Fatalf("request id = %q, want a new 32 character id", id)
+			}
+			if fromContext != id || trackID != id {
+				t.Fatalf("context id = %q, track id = %q, want %q", fromContext, trackID, id)
+			}
+		})
+	}
+}
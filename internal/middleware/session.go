@@ -4,12 +4,10 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
 )
 
 // Session 初始化session
 func Session(secret string) gin.HandlerFunc {
-	GetZapLogger().Info("session", zap.String("secret", secret))
 	store := cookie.NewStore([]byte(secret))
 	//Also set Secure: true if using SSL, you should though
 	store.Options(sessions.Options{HttpOnly: true, MaxAge: 7 * 86400, Path: "/"})
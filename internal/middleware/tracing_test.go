@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"openapphub/internal/tracing"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		_ = tp.Shutdown(context.Background())
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(Tracing())
+	r.GET("/users/:id", func(c *gin.Context) {
+		// 处理器中以 c 为父 context 创建的 span 属于同一个 trace
+		_, span := tracing.Start(c, "child")
+		span.End()
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "GET /users/:id" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span = %q %v", server.Name(), server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one from traceparent", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s", got)
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("child span is not a child of the request span")
+	}
+	if server.Status().Code.String() != "Error" {
+		t.Errorf("status = %v, want Error for 500", server.Status())
+	}
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// AuditTargetUser 以用户为目标的审计记录的 TargetType, TargetID 为用户 ID
+const AuditTargetUser = "user"
+
+// AuditEvent 安全相关操作的审计记录, 只追加, 不修改也不删除.
+// Hash 是 PrevHash 和本条记录内容的 SHA-256, 按 ID 顺序组成哈希链, 见 audit.Verify
+type AuditEvent struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	OccurredAt time.Time `gorm:"precision:6;index" json:"occurred_at"`
+	Action     string    `gorm:"size:64;index" json:"action"`
+	ActorID    uint      `gorm:"index" json:"actor_id,omitempty"` // 执行操作的用户, 匿名请求为 0
+	Actor      string    `gorm:"size:255" json:"actor,omitempty"` // 用户名, 登录失败时为提交的用户名
+	TargetType string    `gorm:"size:32;index:idx_audit_events_target" json:"target_type,omitempty"`
+	TargetID   string    `gorm:"size:255;index:idx_audit_events_target" json:"target_id,omitempty"`
+	IP         string    `gorm:"size:45" json:"ip"`
+	UserAgent  string    `gorm:"size:512" json:"user_agent"`
+	Outcome    string    `gorm:"size:16" json:"outcome"`
+	Reason     string    `gorm:"size:255" json:"reason,omitempty"`
+	RequestID  string    `gorm:"size:128" json:"request_id,omitempty"`
+	Details    string    `gorm:"type:text" json:"details,omitempty"`   // JSON 对象
+	PrevHash   string    `gorm:"size:64;uniqueIndex" json:"prev_hash"` // 唯一, 保证哈希链不分叉
+	Hash       string    `gorm:"size:64;uniqueIndex" json:"hash"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// AuditFilter 查询审计记录的条件, 零值的条件不生效
+type AuditFilter struct {
+	ActorID uint
+	// UserID 与该用户相关的记录: 用户执行的操作, 以及以该用户为目标的操作(例如登录失败)
+	UserID  uint
+	Action  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	// Before 只返回 ID 小于它的记录, 用于翻页
+	Before uint
+	Limit  int
+}
+
+// ListAuditEvents 按 ID 倒序返回符合条件的审计记录
+func ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	query := DB.WithContext(ctx).Order("id DESC").Limit(filter.Limit)
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("(actor_id = ? OR (target_type = ? AND target_id = ?))",
+			filter.UserID, AuditTargetUser, strconv.FormatUint(uint64(filter.UserID), 10))
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("occurred_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("occurred_at < ?", filter.Until)
+	}
+	if filter.Before != 0 {
+		query = query.Where("id < ?", filter.Before)
+	}
+	var events []AuditEvent
+	err := query.Find(&events).Error
+	return events, err
+}
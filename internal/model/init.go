@@ -1,10 +1,9 @@
 package model
 
 import (
-	"log"
+	"openapphub/internal/metrics"
 	"openapphub/internal/util"
-	"os"
-	"time"
+	"openapphub/pkg/redact"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -14,20 +13,10 @@ import (
 // DB 数据库链接单例
 var DB *gorm.DB
 
-// Database 在中间件中初始化mysql链接
-func Database(connString string) {
-	// 初始化GORM日志配置
-	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-		logger.Config{
-			SlowThreshold:             time.Second, // Slow SQL threshold
-			LogLevel:                  logger.Info, // Log level(这里记得根据需求改一下)
-			IgnoreRecordNotFoundError: true,        // Ignore ErrRecordNotFound error for logger
-			Colorful:                  false,       // Disable color
-		},
-	)
+// Database 在中间件中初始化mysql链接, SQL 日志写入 log, 见 GormLogger
+func Database(connString string, log logger.Interface) {
 	db, err := gorm.Open(mysql.Open(connString), &gorm.Config{
-		Logger: newLogger,
+		Logger: log,
 	})
 	// Error
 	if connString == "" || err != nil {
@@ -45,7 +34,31 @@ func Database(connString string) {
 	sqlDB.SetMaxIdleConns(10)
 	//打开
 	sqlDB.SetMaxOpenConns(20)
+	// SQL 执行时间和连接池统计, 见 /metrics
+	if err := registerMetrics(db); err != nil {
+		util.Log().Warning("数据库指标注册失败: %v", err)
+	}
+	if err := metrics.RegisterDB("mysql", sqlDB); err != nil {
+		util.Log().Warning("数据库连接池指标注册失败: %v", err)
+	}
+	// 每条 SQL 的 span, 查询需要通过 WithContext 传入请求的 context
+	if err := registerTracing(db); err != nil {
+		util.Log().Warning("数据库链路追踪注册失败: %v", err)
+	}
 	DB = db
+	util.Log().Info("mysql connected: %s", redact.Current().DSN(connString))
 	// 停止 自动迁移模式
 	// migration()
 }
+
+// Close 关闭数据库连接池, 退出前在所有查询完成后调用
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,6 +12,7 @@ type JWTToken struct {
 	UserID     uint
 	Token      string
 	DeviceInfo string
+	IP         string `gorm:"size:45"` // 登录时的客户端地址
 	ExpiresAt  time.Time
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
@@ -23,26 +25,27 @@ func (JWTToken) TableName() string {
 	return "jwt_tokens"
 }
 
-func CreateJWTToken(userID uint, token string, deviceInfo string, expiresAt time.Time) error {
+func CreateJWTToken(ctx context.Context, userID uint, token string, deviceInfo string, ip string, expiresAt time.Time) error {
 	jwtToken := JWTToken{
 		UserID:     userID,
 		Token:      token,
 		DeviceInfo: deviceInfo,
+		IP:         ip,
 		ExpiresAt:  expiresAt,
 	}
-	return DB.Create(&jwtToken).Error
+	return DB.WithContext(ctx).Create(&jwtToken).Error
 }
 
-func DeleteJWTToken(token string) error {
-	return DB.Where("token = ?", token).Delete(&JWTToken{}).Error
+func DeleteJWTToken(ctx context.Context, token string) error {
+	return DB.WithContext(ctx).Where("token = ?", token).Delete(&JWTToken{}).Error
 }
 
-func DeleteAllJWTTokensForUser(userID uint) error {
-	return DB.Where("user_id = ?", userID).Delete(&JWTToken{}).Error
+func DeleteAllJWTTokensForUser(ctx context.Context, userID uint) error {
+	return DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&JWTToken{}).Error
 }
 
-func GetActiveJWTTokensForUser(userID uint) ([]JWTToken, error) {
+func GetActiveJWTTokensForUser(ctx context.Context, userID uint) ([]JWTToken, error) {
 	var tokens []JWTToken
-	err := DB.Where("user_id = ? AND expires_at > ?", userID, time.Now()).Find(&tokens).Error
+	err := DB.WithContext(ctx).Where("user_id = ? AND expires_at > ?", userID, time.Now()).Find(&tokens).Error
 	return tokens, err
 }
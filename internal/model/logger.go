@@ -0,0 +1,100 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"openapphub/internal/util"
+	"openapphub/pkg/redact"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// GormLogger 把 GORM 的日志写入 zap: 普通 SQL 为 debug, 慢查询为 warn, 出错为 error.
+// 是否输出由 zap 的日志级别决定, 例如 log.packages 中配置 gorm: debug 可以只打开 SQL 日志.
+// SQL 中绑定到敏感列(密码、令牌等)的参数按 redact 规则隐藏
+type GormLogger struct {
+	log           *zap.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger 创建 GORM 日志, slowThreshold 为 0 时不记录慢查询
+func NewGormLogger(log *zap.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{
+		log:           log.WithOptions(zap.WithCaller(false)),
+		level:         logger.Info,
+		slowThreshold: slowThreshold,
+	}
+}
+
+// LogMode 实现 logger.Interface, 在 zap 的级别之外再按 GORM 的级别过滤, 例如 Session(&gorm.Session{Logger: l.LogMode(logger.Silent)})
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.logger(ctx).Info(fmt.Sprintf(msg, args...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.logger(ctx).Warn(fmt.Sprintf(msg, args...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.logger(ctx).Error(fmt.Sprintf(msg, args...), zap.String("source", utils.FileWithLineNum()))
+	}
+}
+
+// Trace 记录一条 SQL, 找不到记录不算错误
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	fields := func() []zap.Field {
+		sql, rows := fc()
+		return []zap.Field{
+			zap.String("source", utils.FileWithLineNum()),
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed),
+		}
+	}
+
+	log := l.logger(ctx)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		log.Error("SQL error", append(fields(), zap.Error(err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		log.Warn("Slow SQL", append(fields(), zap.Duration("threshold", l.slowThreshold))...)
+	case l.level >= logger.Info:
+		// 避免在 debug 关闭时生成 SQL
+		if ce := log.Check(zap.DebugLevel, "SQL"); ce != nil {
+			ce.Write(fields()...)
+		}
+	}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter, 在生成日志中的 SQL 前隐藏敏感参数
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, redact.Current().SQLParams(sql, params)
+}
+
+func (l *GormLogger) logger(ctx context.Context) *zap.Logger {
+	if fields := util.ContextFields(ctx); len(fields) > 0 {
+		return l.log.With(fields...)
+	}
+	return l.log
+}
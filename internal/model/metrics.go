@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	"openapphub/internal/metrics"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// metricsStartKey 记录 SQL 开始时间的 key
+const metricsStartKey = "metrics:start"
+
+// registerMetrics 在 GORM 的各类操作前后记录 SQL 执行时间, 见 metrics.DBQueryDuration
+func registerMetrics(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		db.InstanceSet(metricsStartKey, time.Now())
+	}
+	after := func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			value, ok := db.InstanceGet(metricsStartKey)
+			if !ok {
+				return
+			}
+			start, _ := value.(time.Time)
+			status := "ok"
+			if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+				status = "error"
+			}
+			metrics.DBQueryDuration.WithLabelValues(operation, db.Statement.Table, status).Observe(time.Since(start).Seconds())
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", before),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", after("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", before),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", after("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", before),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", after("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", before),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", after("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")),
+	)
+}
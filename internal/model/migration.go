@@ -1,8 +1,11 @@
 package model
 
+// SchemaVersion database/migrations 中最新的迁移版本, 就绪检查要求数据库至少是这个版本, 添加迁移时同步修改
+const SchemaVersion = 7
+
 //执行数据迁移
 
 func migration() {
 	// 自动迁移模式
-	_ = DB.AutoMigrate(&User{})
+	_ = DB.AutoMigrate(&User{}, &AuditEvent{})
 }
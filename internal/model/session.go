@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,6 +12,7 @@ type Session struct {
 	UserID     uint
 	SessionID  string
 	DeviceInfo string
+	IP         string `gorm:"size:45"` // 登录时的客户端地址
 	ExpiresAt  time.Time
 	UpdatedAt  time.Time
 	DeletedAt  gorm.DeletedAt `gorm:"index"`
@@ -20,26 +22,27 @@ func (Session) TableName() string {
 	return "sessions"
 }
 
-func CreateSession(userID uint, sessionID string, deviceInfo string, expiresAt time.Time) error {
+func CreateSession(ctx context.Context, userID uint, sessionID string, deviceInfo string, ip string, expiresAt time.Time) error {
 	session := Session{
 		UserID:     userID,
 		SessionID:  sessionID,
 		DeviceInfo: deviceInfo,
+		IP:         ip,
 		ExpiresAt:  expiresAt,
 	}
-	return DB.Create(&session).Error
+	return DB.WithContext(ctx).Create(&session).Error
 }
 
-func DeleteSession(sessionID string) error {
-	return DB.Where("session_id = ?", sessionID).Delete(&Session{}).Error
+func DeleteSession(ctx context.Context, sessionID string) error {
+	return DB.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&Session{}).Error
 }
 
-func DeleteAllSessionsForUser(userID uint) error {
-	return DB.Where("user_id = ?", userID).Delete(&Session{}).Error
+func DeleteAllSessionsForUser(ctx context.Context, userID uint) error {
+	return DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&Session{}).Error
 }
 
-func GetActiveSessionsForUser(userID uint) ([]Session, error) {
+func GetActiveSessionsForUser(ctx context.Context, userID uint) ([]Session, error) {
 	var sessions []Session
-	err := DB.Where("user_id = ? AND expires_at > ?", userID, time.Now()).Find(&sessions).Error
+	err := DB.WithContext(ctx).Where("user_id = ? AND expires_at > ?", userID, time.Now()).Find(&sessions).Error
 	return sessions, err
 }
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"errors"
+	"openapphub/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// tracingSpanKey 保存 SQL 的 span 的 key
+const tracingSpanKey = "tracing:span"
+
+// registerTracing 为 GORM 的每次操作创建 span, 作为 WithContext 传入的请求 span 的子 span.
+// 只记录带占位符的 SQL, 不记录参数
+func registerTracing(db *gorm.DB) error {
+	before := func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			ctx, span := tracing.Start(db.Statement.Context, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperationName(operation)))
+			db.Statement.Context = ctx
+			db.InstanceSet(tracingSpanKey, span)
+		}
+	}
+	after := func(db *gorm.DB) {
+		value, ok := db.InstanceGet(tracingSpanKey)
+		if !ok {
+			return
+		}
+		span, _ := value.(trace.Span)
+		if span == nil {
+			return
+		}
+		span.SetAttributes(
+			semconv.DBCollectionName(db.Statement.Table),
+			semconv.DBQueryText(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		tracing.End(span, err)
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", before("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", after),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", before("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", after),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", before("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", after),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", after),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", before("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", after),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", after),
+	)
+}
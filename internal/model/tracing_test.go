@@ -0,0 +1,102 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errStubExec = errors.New("stub exec failed")
+
+// stubConnector 查询总是返回空结果, 执行总是失败, 不需要真实的数据库
+type stubConnector struct{}
+
+func (stubConnector) Connect(context.Context) (driver.Conn, error) { return stubConn{}, nil }
+func (stubConnector) Driver() driver.Driver                        { return stubDriver{} }
+
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("prepare not supported") }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return nil, errors.New("transactions not supported") }
+
+func (stubConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return stubRows{}, nil
+}
+
+func (stubConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, errStubExec
+}
+
+type stubRows struct{}
+
+func (stubRows) Columns() []string         { return []string{"id"} }
+func (stubRows) Close() error              { return nil }
+func (stubRows) Next([]driver.Value) error { return io.EOF }
+
+type tracedRow struct {
+	ID uint
+}
+
+func TestTracingCallbacks(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		_ = tp.Shutdown(context.Background())
+	})
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(stubConnector{}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registerTracing(db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	if err := db.WithContext(ctx).First(&tracedRow{}).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("First error = %v, want ErrRecordNotFound", err)
+	}
+	if err := db.WithContext(ctx).Exec("UPDATE traced_rows SET id = ?", 1).Error; !errors.Is(err, errStubExec) {
+		t.Fatalf("Exec error = %v, want %v", err, errStubExec)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want one per query and the parent", len(spans))
+	}
+	query, raw := spans[0], spans[1]
+	for _, span := range []sdktrace.ReadOnlySpan{query, raw} {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", span.Name())
+		}
+	}
+	if query.Name() != "gorm.query" || query.Status().Code == codes.Error {
+		t.Errorf("query span = %q %v, record not found should not be an error", query.Name(), query.Status())
+	}
+	if raw.Name() != "gorm.raw" || raw.Status().Code != codes.Error {
+		t.Errorf("raw span = %q %v, want an error status", raw.Name(), raw.Status())
+	}
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"context"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -12,7 +14,9 @@ type User struct {
 	PasswordDigest string
 	Nickname       string
 	Status         string
+	Tier           string `gorm:"size:50;default:free"`
 	Avatar         string `gorm:"size:1000"`
+	Locale         string `gorm:"size:16"` // 偏好的语言, 为空时按 Accept-Language 选择
 }
 
 const (
@@ -26,10 +30,20 @@ const (
 	Suspend string = "suspend"
 )
 
+// 用户等级, 决定限流额度
+const (
+	// TierFree 免费用户
+	TierFree string = "free"
+	// TierPro 付费用户
+	TierPro string = "pro"
+	// TierInternal 内部账号
+	TierInternal string = "internal"
+)
+
 // GetUser 用ID获取用户
-func GetUser(ID interface{}) (User, error) {
+func GetUser(ctx context.Context, ID interface{}) (User, error) {
 	var user User
-	result := DB.First(&user, ID)
+	result := DB.WithContext(ctx).First(&user, ID)
 	return user, result.Error
 }
 
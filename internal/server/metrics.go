@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+	"openapphub/internal/config"
+	"openapphub/internal/metrics"
+	"time"
+)
+
+// NewMetricsServer 单独监听的 Prometheus 指标服务, 应只监听内网地址.
+// 没有启用指标或没有配置 metrics.listen 时返回 nil, 指标由主服务的管理接口输出
+func NewMetricsServer(conf *config.Config) *http.Server {
+	if !conf.Metrics.Enabled || conf.Metrics.Listen == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(conf.Metrics.Path, metrics.Handler())
+	return &http.Server{
+		Addr:              conf.Metrics.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
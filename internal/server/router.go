@@ -3,8 +3,12 @@ package server
 import (
 	"fmt"
 	"openapphub/internal/api"
+	"openapphub/internal/auth"
+	"openapphub/internal/config"
+	"openapphub/internal/metrics"
 	"openapphub/internal/middleware"
-	"os"
+	"openapphub/internal/model"
+	"openapphub/internal/util"
 	"time"
 
 	_ "openapphub/docs" // This line is important
@@ -15,66 +19,186 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// 限流策略, 同一个策略内的多个限额同时生效
+var (
+	// globalRateLimit 所有请求共享的突发限额, 缓存命中的请求同样计数
+	globalRateLimit = middleware.RateLimitPolicy{Name: "global", Limits: []string{"20-S"}}
+	// publicRateLimit 公开接口
+	publicRateLimit = middleware.RateLimitPolicy{Name: "public", Limits: []string{"100-H"}}
+	// loginRateLimit 登录, 按 IP 限制以防止暴力破解
+	loginRateLimit = middleware.RateLimitPolicy{Name: "login", Limits: []string{"10-M"}}
+	// registerRateLimit 注册, 按 IP 限制
+	registerRateLimit = middleware.RateLimitPolicy{Name: "register", Limits: []string{"5-H"}}
+	// apiRateLimit 需要登录的接口, 按用户限制, 额度由用户等级决定
+	apiRateLimit = middleware.RateLimitPolicy{
+		Name:        "api",
+		Limits:      []string{"10-S", "1000-H"},
+		LimitByUser: true,
+		Tiers: map[string][]string{
+			model.TierPro:      {"50-S", "10000-H"},
+			model.TierInternal: {},
+		},
+	}
+)
+
+// 并发限制策略, 排队超时或队列已满时返回 503
+var (
+	// globalConcurrency 所有请求共享的并发上限
+	globalConcurrency = middleware.ConcurrencyPolicy{Name: "global", MaxInFlight: 500, MaxQueue: 1000, MaxWait: 2 * time.Second}
+	// apiConcurrency 需要登录的接口, 通常更慢, 单独限制避免拖垮公开接口
+	apiConcurrency = middleware.ConcurrencyPolicy{Name: "api", MaxInFlight: 200, MaxQueue: 200, MaxWait: time.Second}
+)
+
+// IP 过滤策略, 运行时规则通过 /ipfilter 接口维护
+var (
+	// globalIPFilter 全局黑名单, ip_filter.denylist 和 ip_filter.deny_countries 配置静态规则
+	globalIPFilter = middleware.IPFilterPolicy{Name: "global"}
+	// adminIPFilter 管理接口白名单, ip_filter.admin_allowed_networks 配置允许的网段, 默认只允许内网访问
+	adminIPFilter = middleware.IPFilterPolicy{Name: "admin"}
+)
+
 // NewRouter 路由配置
-func NewRouter() *gin.Engine {
+func NewRouter(conf *config.Config) *gin.Engine {
+	// 参数校验错误使用 json 字段名并按请求语言翻译
+	if err := api.SetupValidator(); err != nil {
+		util.Log().Panic("参数校验配置失败: %v", err)
+	}
+
 	r := gin.Default()
+	// 客户端地址由 RealIP 中间件按可信代理配置解析, gin 自身不信任任何代理请求头
+	_ = r.SetTrustedProxies(nil)
+	// c.Value 等读不到时使用 c.Request.Context(), 把 c 作为 context 传给数据库和缓存时可以带上请求的 span
+	r.ContextWithFallback = true
 
 	// 中间件, 顺序不能改
+	// 请求 ID 需要在最前面, 之后的所有日志都带有请求 ID
+	r.Use(middleware.RequestID())
+	// 按可信代理配置解析客户端地址, 需要在指标、链路追踪和日志之前, 它们记录的都是真实地址
+	r.Use(middleware.RealIP())
+	// 存活和就绪探针在限流、缓存和访问日志之前注册, 不经过之后的中间件, 也不经过 IP 白名单, 只返回总体状态
+	r.GET("/healthz", api.Healthz)
+	r.GET("/readyz", api.Readyz)
+	// 请求数和处理时间, 包括被限流、IP 过滤等拒绝的请求
+	r.Use(middleware.Metrics())
+	// 请求的 span, 之后的日志带有 trace ID
+	r.Use(middleware.Tracing())
 	r.Use(middleware.Cors())
 	// 使用安全中间件
 	r.Use(middleware.SecureMiddleware())
 	// 使用日志中间件
 	r.Use(middleware.Logger())
 	r.Use(middleware.RecoveryWithZap())
-	// 使用限流中间件
-	r.Use(middleware.RateLimiter(middleware.RateLimiterConfig{
-		RateString:  "100-H",
-		LimitByUser: false,
-	}))
+	// 按 Accept-Language 选择响应消息的语言, 之后的中间件返回的消息都会翻译
+	r.Use(middleware.Locale())
+	// 使用全局 IP 黑名单
+	blocklist := globalIPFilter
+	blocklist.Deny = conf.IPFilter.Denylist
+	blocklist.DenyCountries = conf.IPFilter.DenyCountries
+	r.Use(middleware.IPFilter(blocklist))
+	// 使用全局限流中间件, 各路由组的限流策略在下面单独配置
+	r.Use(middleware.RateLimit(globalRateLimit))
 	// 使用 gzip
 	r.Use(gzip.Gzip(gzip.DefaultCompression))
 
 	// 根据认证模式选择中间件
-	authMode := os.Getenv("AUTH_MODE")
-
-	if authMode != "jwt" {
-		r.Use(middleware.Session(os.Getenv("SESSION_SECRET")))
+	if conf.Auth.Mode != auth.ModeJWT {
+		r.Use(middleware.Session(conf.Auth.SessionSecret))
 	}
 	r.Use(middleware.CurrentUser())
+	// 并发限制需要在 CurrentUser 之后, 以便按用户等级排队
+	r.Use(middleware.ConcurrencyLimit(globalConcurrency))
+
+	// 管理接口的 IP 白名单
+	adminPolicy := adminIPFilter
+	adminPolicy.Allow = conf.IPFilter.AdminAllowedNetworks
+	adminOnly := middleware.IPFilter(adminPolicy)
+	adminToken := middleware.AdminAuth(conf.Auth.AdminToken)
+	// 设置认证状态或有副作用的路由, 即使 cache.yaml 中有匹配的策略也不缓存
+	noCache := middleware.NoCache()
+	// 按 cache.yaml 中的策略缓存响应. 放在各路由组的认证、过滤和限流之后, 缓存命中的请求同样要经过它们
+	cacheResponses := middleware.Cache()
+
+	// Prometheus 指标, 配置了 metrics.listen 时由单独的服务输出, 见 NewMetricsServer
+	if conf.Metrics.Enabled && conf.Metrics.Listen == "" {
+		r.GET(conf.Metrics.Path, adminOnly, gin.WrapH(metrics.Handler()))
+	}
 
 	// Swagger documentation
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/swagger/*any", adminOnly, ginSwagger.WrapHandler(swaggerFiles.Handler))
 	// API 路由
 	apiVersion := "v1" // 可以轻松更改 API 版本
 	// v1 := r.Group("/api/v1")
 	v1 := r.Group(fmt.Sprintf("/api/%s", apiVersion))
 	{
-		// 公开路由
-		v1.GET("ping", middleware.CacheMiddleware(5*time.Minute), api.Ping)
-		// 缓存 ping
-		v1.POST("ping", middleware.CacheMiddleware(5*time.Minute), api.Ping)
+		// 用户注册
+		v1.POST("user/register", noCache, middleware.RateLimit(registerRateLimit), api.UserRegister)
 		// 用户登录
-		v1.POST("user/register", api.UserRegister)
-		// 用户登录
-		v1.POST("user/login", middleware.CacheMiddleware(5*time.Minute), api.UserLogin)
-		// 刷新用户token
-		v1.POST("user/refresh", api.RefreshToken)
+		v1.POST("user/login", noCache, middleware.RateLimit(loginRateLimit), api.UserLogin)
+
+		publicLimit := middleware.RateLimit(publicRateLimit)
+
+		// 公开路由
+		public := v1.Group("")
+		public.Use(publicLimit, cacheResponses)
+		{
+			public.GET("ping", api.Ping)
+			public.POST("ping", api.Ping)
+			// 刷新用户token
+			public.POST("user/refresh", noCache, api.RefreshToken)
+		}
+
+		// 管理接口, 只允许白名单内的网络访问, 还需要管理令牌
+		admin := v1.Group("")
+		admin.Use(noCache, adminOnly, adminToken, publicLimit)
+		{
+			// 缓存管理
+			admin.POST("cache/clear", api.ClearCacheByPrefix)
+			admin.POST("cache/refresh", api.RefreshCache)
+			admin.POST("cache/invalidate", api.InvalidateCache)
+			admin.GET("cache/entries", api.ListCacheEntries)
+			admin.GET("cache/entry", api.InspectCacheEntry)
+			admin.GET("cache/stats", api.CacheStats)
+			admin.POST("cache/warm", api.WarmCache(r))
+			admin.GET("cache/policies", api.CachePolicies)
+			admin.POST("cache/policies/reload", api.ReloadCachePolicies)
+
+			// 限流状态
+			admin.GET("ratelimit/stats", api.RateLimitStats)
+			admin.GET("concurrency/stats", api.ConcurrencyStats)
+
+			// IP 黑白名单
+			admin.GET("ipfilter", api.ListIPFilters)
+			admin.POST("ipfilter/add", api.AddIPFilterEntry)
+			admin.POST("ipfilter/remove", api.RemoveIPFilterEntry)
+
+			// 当前生效的配置和热更新
+			admin.GET("config", api.CurrentConfig)
+			admin.POST("config/reload", api.ReloadConfig)
 
-		// 缓存管理, 常规情况下只允许管理员执行
-		v1.POST("cache/clear", api.ClearCacheByPrefix)
-		v1.POST("cache/refresh", api.RefreshCache)
-		v1.POST("cache/invalidate", api.InvalidateCache)
+			// 运行时修改日志级别
+			admin.GET("log/level", api.GetLogLevel)
+			admin.PUT("log/level", api.SetLogLevel)
+
+			// 审计记录
+			admin.GET("audit/events", api.ListAuditEvents)
+			admin.GET("audit/verify", api.VerifyAuditEvents)
+
+			// 就绪检查每一项的结果, /readyz 只返回总体状态
+			admin.GET("health/checks", api.HealthChecks)
+		}
 
 		// 需要认证的路由
 		auth := v1.Group("")
-		auth.Use(middleware.AuthRequired())
+		auth.Use(middleware.AuthRequired(), middleware.RateLimit(apiRateLimit), middleware.ConcurrencyLimit(apiConcurrency), cacheResponses)
 		{
 			// User Routing
 			auth.GET("user/me", api.UserMe)
-			auth.DELETE("user/logout", api.UserLogout)
-			auth.POST("user/logout/all", api.UserLogoutAll)
-			auth.POST("user/logout/:device_id", api.UserLogoutDevice)
+			auth.PUT("user/locale", api.UserSetLocale)
+			auth.DELETE("user/logout", noCache, api.UserLogout)
+			auth.POST("user/logout/all", noCache, api.UserLogoutAll)
+			auth.POST("user/logout/:device_id", noCache, api.UserLogoutDevice)
 			auth.GET("user/devices", api.UserDevices)
+			auth.GET("user/security-events", api.UserSecurityEvents)
 		}
 	}
 	return r
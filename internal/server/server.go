@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http"
+	"openapphub/internal/config"
+	"time"
+)
+
+// NewHTTPServer 按配置的超时和请求头大小创建 HTTP 服务, 退出时通过 Shutdown 等待处理中的请求完成
+func NewHTTPServer(conf *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + conf.Server.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(conf.Server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(conf.Server.ReadTimeout),
+		WriteTimeout:      time.Duration(conf.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(conf.Server.IdleTimeout),
+		MaxHeaderBytes:    conf.Server.MaxHeaderBytes,
+	}
+}
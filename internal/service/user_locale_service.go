@@ -0,0 +1,31 @@
+package service
+
+import (
+	"openapphub/internal/model"
+	"openapphub/pkg/i18n"
+	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserLocaleService 设置用户偏好的语言
+type UserLocaleService struct {
+	Locale string `form:"locale" json:"locale" binding:"max=16"` // 为空时清除偏好, 按 Accept-Language 选择
+}
+
+// Update 保存语言偏好, 之后的响应消息使用该语言
+func (service *UserLocaleService) Update(c *gin.Context, user *model.User) serializer.Response {
+	locale := i18n.Match(service.Locale)
+	if service.Locale != "" && locale == "" {
+		return serializer.ParamErr(i18n.T(c, "User.UnsupportedLocale", i18n.Params{"locale": service.Locale}), nil)
+	}
+
+	if err := model.DB.WithContext(c).Model(user).Update("locale", locale).Error; err != nil {
+		return serializer.DBErr(i18n.T(c, "Common.DBErr"), err)
+	}
+
+	c.Set(i18n.ContextKey, i18n.Negotiate(locale, c.GetHeader("Accept-Language")))
+	res := serializer.BuildUserResponse(*user)
+	res.Msg = i18n.T(c, "User.LocaleUpdated", i18n.Params{"locale": i18n.Locale(c)})
+	return res
+}
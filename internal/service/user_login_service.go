@@ -1,11 +1,14 @@
 package service
 
 import (
+	"fmt"
+	"openapphub/internal/audit"
 	"openapphub/internal/auth"
+	"openapphub/internal/middleware"
 	"openapphub/internal/model"
 	"openapphub/internal/util"
+	"openapphub/pkg/i18n"
 	"openapphub/pkg/serializer"
-	"os"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -22,32 +25,59 @@ type UserLoginService struct {
 func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
 	var user model.User
 
-	if err := model.DB.Where("user_name = ?", service.UserName).First(&user).Error; err != nil {
-		return serializer.ParamErr("账号或密码错误", nil)
+	if err := model.DB.WithContext(c).Where("user_name = ?", service.UserName).First(&user).Error; err != nil {
+		service.audit(c, nil, audit.OutcomeFailure, "unknown_user")
+		return serializer.ParamErr(i18n.T(c, "User.InvalidCredentials"), nil)
 	}
 
 	if !user.CheckPassword(service.Password) {
-		return serializer.ParamErr("账号或密码错误", nil)
+		service.audit(c, &user, audit.OutcomeFailure, "invalid_password")
+		return serializer.ParamErr(i18n.T(c, "User.InvalidCredentials"), nil)
 	}
 
-	authMode := os.Getenv("AUTH_MODE")
-	if authMode == "jwt" {
-		return service.loginWithJWT(c, user)
+	var res serializer.Response
+	authMode := auth.Mode()
+	if authMode == auth.ModeJWT {
+		res = service.loginWithJWT(c, user)
 	} else {
-		return service.loginWithSession(c, user)
+		res = service.loginWithSession(c, user)
 	}
+	if res.Code == 0 {
+		service.audit(c, &user, audit.OutcomeSuccess, "")
+	} else {
+		service.audit(c, &user, audit.OutcomeError, fmt.Sprintf("code %d", res.Code))
+	}
+	return res
+}
+
+// audit 记录登录事件, 登录失败时 actor 为提交的用户名, 用户存在时以该用户为目标
+func (service *UserLoginService) audit(c *gin.Context, user *model.User, outcome, reason string) {
+	e := audit.FromRequest(c, audit.ActionLogin)
+	e.Actor = service.UserName
+	e.Outcome = outcome
+	e.Reason = reason
+	if user != nil {
+		e.SetUserTarget(user.ID)
+		if outcome == audit.OutcomeSuccess {
+			e.ActorID = user.ID
+		}
+	}
+	if service.DeviceInfo != "" {
+		e.Details = map[string]string{"device_info": service.DeviceInfo}
+	}
+	audit.Record(e)
 }
 
-func (service *UserLoginService) loginWithJWT(_ *gin.Context, user model.User) serializer.Response {
+func (service *UserLoginService) loginWithJWT(c *gin.Context, user model.User) serializer.Response {
 	accessToken, refreshToken, err := auth.GenerateTokenPair(user)
 	if err != nil {
-		return serializer.Err(serializer.CodeEncryptError, "生成令牌失败", err)
+		return serializer.Err(serializer.CodeEncryptError, i18n.T(c, "User.GenerateTokenFailed"), err)
 	}
 
 	expiresAt := time.Now().Add(time.Hour * 24) // Token expires in 24 hours
-	err = model.CreateJWTToken(user.ID, accessToken, service.DeviceInfo, expiresAt)
+	err = model.CreateJWTToken(c, user.ID, accessToken, service.DeviceInfo, middleware.ClientIP(c), expiresAt)
 	if err != nil {
-		return serializer.DBErr("保存令牌失败", err)
+		return serializer.DBErr(i18n.T(c, "User.SaveTokenFailed"), err)
 	}
 
 	return serializer.BuildUserResponseWithToken(user, accessToken, refreshToken)
@@ -60,13 +90,13 @@ func (service *UserLoginService) loginWithSession(c *gin.Context, user model.Use
 	s.Set("session_id", sessionID)
 	err := s.Save()
 	if err != nil {
-		return serializer.Err(serializer.CodeEncryptError, "保存会话失败", err)
+		return serializer.Err(serializer.CodeEncryptError, i18n.T(c, "User.SaveSessionFailed"), err)
 	}
 
 	expiresAt := time.Now().Add(time.Hour * 24 * 7) // Session expires in 7 days
-	err = model.CreateSession(user.ID, sessionID, service.DeviceInfo, expiresAt)
+	err = model.CreateSession(c, user.ID, sessionID, service.DeviceInfo, middleware.ClientIP(c), expiresAt)
 	if err != nil {
-		return serializer.DBErr("保存会话失败", err)
+		return serializer.DBErr(i18n.T(c, "User.SaveSessionFailed"), err)
 	}
 
 	return serializer.BuildUserResponse(user)
@@ -2,7 +2,10 @@ package service
 
 import (
 	"openapphub/internal/model"
+	"openapphub/pkg/i18n"
 	"openapphub/pkg/serializer"
+
+	"github.com/gin-gonic/gin"
 )
 
 // UserRegisterService 管理用户注册服务
@@ -11,32 +14,40 @@ type UserRegisterService struct {
 	UserName        string `form:"user_name" json:"user_name" binding:"required,min=5,max=30"`
 	Password        string `form:"password" json:"password" binding:"required,min=8,max=40"`
 	PasswordConfirm string `form:"password_confirm" json:"password_confirm" binding:"required,min=8,max=40"`
+	Locale          string `form:"locale" json:"locale" binding:"max=16"` // 可选, 偏好的语言, 例如 en-US
 }
 
 // valid 验证表单
-func (service *UserRegisterService) valid() *serializer.Response {
+func (service *UserRegisterService) valid(c *gin.Context) *serializer.Response {
 	if service.PasswordConfirm != service.Password {
 		return &serializer.Response{
 			Code: 40001,
-			Msg:  "两次输入的密码不相同",
+			Msg:  i18n.T(c, "User.PasswordMismatch"),
 		}
 	}
 
 	count := int64(0)
-	model.DB.Model(&model.User{}).Where("nickname = ?", service.Nickname).Count(&count)
+	model.DB.WithContext(c).Model(&model.User{}).Where("nickname = ?", service.Nickname).Count(&count)
 	if count > 0 {
 		return &serializer.Response{
 			Code: 40001,
-			Msg:  "昵称被占用",
+			Msg:  i18n.T(c, "User.NicknameTaken"),
 		}
 	}
 
 	count = 0
-	model.DB.Model(&model.User{}).Where("user_name = ?", service.UserName).Count(&count)
+	model.DB.WithContext(c).Model(&model.User{}).Where("user_name = ?", service.UserName).Count(&count)
 	if count > 0 {
 		return &serializer.Response{
 			Code: 40001,
-			Msg:  "用户名已经注册",
+			Msg:  i18n.T(c, "User.UserNameTaken"),
+		}
+	}
+
+	if service.Locale != "" && i18n.Match(service.Locale) == "" {
+		return &serializer.Response{
+			Code: 40001,
+			Msg:  i18n.T(c, "User.UnsupportedLocale", i18n.Params{"locale": service.Locale}),
 		}
 	}
 
@@ -44,15 +55,17 @@ func (service *UserRegisterService) valid() *serializer.Response {
 }
 
 // Register 用户注册
-func (service *UserRegisterService) Register() serializer.Response {
+func (service *UserRegisterService) Register(c *gin.Context) serializer.Response {
 	user := model.User{
 		Nickname: service.Nickname,
 		UserName: service.UserName,
 		Status:   model.Active,
+		Tier:     model.TierFree,
+		Locale:   i18n.Match(service.Locale),
 	}
 
 	// 表单验证
-	if err := service.valid(); err != nil {
+	if err := service.valid(c); err != nil {
 		return *err
 	}
 
@@ -60,14 +73,14 @@ func (service *UserRegisterService) Register() serializer.Response {
 	if err := user.SetPassword(service.Password); err != nil {
 		return serializer.Err(
 			serializer.CodeEncryptError,
-			"密码加密失败",
+			i18n.T(c, "User.EncryptPasswordFailed"),
 			err,
 		)
 	}
 
 	// 创建用户
-	if err := model.DB.Create(&user).Error; err != nil {
-		return serializer.ParamErr("注册失败", err)
+	if err := model.DB.WithContext(c).Create(&user).Error; err != nil {
+		return serializer.ParamErr(i18n.T(c, "User.RegisterFailed"), err)
 	}
 
 	return serializer.BuildUserResponse(user)
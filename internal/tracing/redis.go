@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redisHook 为每条 Redis 命令和每个管道创建 span, 只记录命令名, 不记录键和值
+type redisHook struct{}
+
+// RedisHook 返回 go-redis 的 hook, 通过 client.AddHook 添加
+func RedisHook() redis.Hook {
+	return redisHook{}
+}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := Start(ctx, "redis.dial", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.ServerAddress(addr)))
+		conn, err := next(ctx, network, addr)
+		End(span, err)
+		return conn, err
+	}
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := Start(ctx, "redis."+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName(cmd.Name())))
+		err := next(ctx, cmd)
+		End(span, redisError(err))
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := Start(ctx, "redis.pipeline", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName("pipeline"), attribute.Int("db.operation.batch.size", len(cmds))))
+		err := next(ctx, cmds)
+		End(span, redisError(err))
+		return err
+	}
+}
+
+// redisError 键不存在不算错误
+func redisError(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
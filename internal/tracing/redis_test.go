@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestRedisHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		_ = tp.Shutdown(context.Background())
+	})
+
+	mr := miniredis.RunT(t)
+	mr.Set("name", "value")
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	client.AddHook(RedisHook())
+
+	ctx := context.Background()
+	if err := client.Get(ctx, "missing").Err(); !errors.Is(err, redis.Nil) {
+		t.Fatalf("get missing key = %v, want redis.Nil", err)
+	}
+	if _, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "missing")
+		return nil
+	}); !errors.Is(err, redis.Nil) {
+		t.Fatalf("pipeline = %v, want redis.Nil", err)
+	}
+	if err := client.Incr(ctx, "name").Err(); err == nil {
+		t.Fatal("expected incr on a string to fail")
+	}
+
+	status := map[string]codes.Code{}
+	for _, span := range recorder.Ended() {
+		status[span.Name()] = span.Status().Code
+	}
+	want := map[string]codes.Code{
+		"redis.dial":     codes.Unset,
+		"redis.get":      codes.Unset,
+		"redis.pipeline": codes.Unset,
+		"redis.incr":     codes.Error,
+	}
+	for name, code := range want {
+		got, ok := status[name]
+		if !ok {
+			t.Errorf("missing %s span in %v", name, status)
+		} else if got != code {
+			t.Errorf("%s status = %v, want %v", name, got, code)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package tracing 初始化 OpenTelemetry 链路追踪.
+// HTTP 请求、SQL、Redis 命令、缓存查找和令牌解析都会创建 span, 通过 W3C traceparent 请求头与上下游串联.
+// 没有配置导出器时仍然传递上游的 trace ID, 日志中可以按 trace_id 关联, 只是不记录本服务的 span
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 导出器类型
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"   // OTLP/HTTP, 发送到 collector 或 Jaeger、Tempo 等后端
+	ExporterStdout = "stdout" // 本地调试时输出到标准输出
+	ExporterFile   = "file"   // 本地调试时按行写入 JSON 文件
+)
+
+// instrumentationName 本服务创建的 span 的 instrumentation scope
+const instrumentationName = "openapphub"
+
+// Options 链路追踪配置
+type Options struct {
+	Exporter string
+	// Endpoint OTLP/HTTP 地址, 例如 http://127.0.0.1:4318; 为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT 等环境变量
+	Endpoint string
+	// File exporter 为 file 时写入的文件
+	File string
+	// SampleRatio 没有上游采样决定时的采样比例, 0 到 1; 上游请求已采样时跟随上游
+	SampleRatio float64
+	ServiceName string
+}
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+	closer   io.Closer
+)
+
+// ValidateOptions 校验配置, 返回所有错误
+func ValidateOptions(opts Options) error {
+	var errs []error
+	switch opts.Exporter {
+	case ExporterNone, ExporterOTLP, ExporterStdout:
+	case ExporterFile:
+		if opts.File == "" {
+			errs = append(errs, errors.New("file: must be set when exporter is file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("exporter: must be one of none, otlp, stdout, file, got %q", opts.Exporter))
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("sample_ratio: must be between 0 and 1, got %v", opts.SampleRatio))
+	}
+	if opts.Exporter != ExporterNone && opts.ServiceName == "" {
+		errs = append(errs, errors.New("service_name: must not be empty"))
+	}
+	return errors.Join(errs...)
+}
+
+// Init 设置 W3C traceparent 和 baggage 的传播方式, 并按配置创建导出 span 的 TracerProvider
+func Init(opts Options) error {
+	if err := ValidateOptions(opts); err != nil {
+		return err
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if opts.Exporter == ExporterNone {
+		return nil
+	}
+
+	ctx := context.Background()
+	var (
+		exporter sdktrace.SpanExporter
+		file     *os.File
+		err      error
+	)
+	switch opts.Exporter {
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if err = os.MkdirAll(filepath.Dir(opts.File), 0o755); err != nil {
+			return err
+		}
+		if file, err = os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return fmt.Errorf("create %s exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(opts.ServiceName)),
+	)
+	if err != nil {
+		return err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	mu.Lock()
+	provider = tp
+	if file != nil {
+		closer = file
+	}
+	mu.Unlock()
+	return nil
+}
+
+// Shutdown 导出尚未发送的 span 并关闭导出器, 退出前调用
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	tp, c := provider, closer
+	provider, closer = nil, nil
+	mu.Unlock()
+
+	var errs []error
+	if tp != nil {
+		errs = append(errs, tp.Shutdown(ctx))
+	}
+	if c != nil {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Tracer 返回本服务的 tracer, 每次从全局 TracerProvider 获取, 测试中可以替换
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start 创建子 span, 与 Tracer().Start 相同
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End 结束 span, err 不为 nil 时记录错误
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// background 请求结束后仍在执行的后台任务, 例如异步写入缓存, 退出前需要等待它们完成
+var background sync.WaitGroup
+
+// Go 在新的 goroutine 中执行后台任务, 退出前 WaitBackground 会等待它完成.
+// 任务 panic 时记录错误日志, 不会导致进程退出
+func Go(name string, fn func()) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				Log().Error("后台任务 %s panic: %v\n%s", name, r, debug.Stack())
+			}
+		}()
+		fn()
+	}()
+}
+
+// WaitBackground 等待 Go 启动的后台任务完成或 ctx 结束, 需要在 HTTP 服务停止接收请求之后调用
+func WaitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background tasks not finished: %w", ctx.Err())
+	}
+}
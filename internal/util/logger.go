@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -13,10 +14,10 @@ type Logger struct {
 
 var logger *Logger
 
-// BuildLogger 构建logger
+// BuildLogger 构建logger, 日志中的调用位置跳过 Logger 自身的方法
 func BuildLogger(zapLogger *zap.Logger) {
 	logger = &Logger{
-		zapLogger: zapLogger,
+		zapLogger: zapLogger.WithOptions(zap.AddCallerSkip(1)),
 	}
 }
 
@@ -28,6 +29,15 @@ func Log() *Logger {
 	return logger
 }
 
+// LogCtx 返回带有请求 ID 和 trace ID 的日志对象, 处理请求的代码应使用它代替 Log
+func LogCtx(ctx context.Context) *Logger {
+	ll := Log()
+	if fields := ContextFields(ctx); len(fields) > 0 {
+		return &Logger{zapLogger: ll.zapLogger.With(fields...)}
+	}
+	return ll
+}
+
 // Println 打印
 func (ll *Logger) Println(msg string) {
 	ll.zapLogger.Info(msg)
@@ -0,0 +1,28 @@
+package util
+
+import "context"
+
+// RequestIDKey 请求 ID 在 gin.Context 中的 key, 也是日志中的字段名
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID 返回带有请求 ID 的 context, 用于传给不依赖 gin 的代码
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 返回请求 ID, ctx 可以是 *gin.Context 或 WithRequestID 返回的 context, 没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	// *gin.Context 按字符串 key 读取 c.Set 的值
+	if id, ok := ctx.Value(RequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
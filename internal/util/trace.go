@@ -0,0 +1,30 @@
+package util
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 日志中 trace ID 和 span ID 的字段名, 与 OpenTelemetry 的日志关联约定一致
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// ContextFields 返回关联请求的日志字段: 请求 ID, 以及链路追踪的 trace ID 和 span ID.
+// ctx 为 *gin.Context 时需要开启 ContextWithFallback 才能读到 span
+func ContextFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+	var fields []zap.Field
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, zap.String(TraceIDKey, sc.TraceID().String()), zap.String(SpanIDKey, sc.SpanID().String()))
+	}
+	return fields
+}
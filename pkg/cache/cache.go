@@ -2,92 +2,129 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"openapphub/internal/util"
-	"os"
-	"strconv"
 	"time"
+)
 
-	"github.com/redis/go-redis/v9"
+// 缓存后端驱动
+const (
+	// DriverRedis 单机 Redis
+	DriverRedis = "redis"
+	// DriverCluster Redis Cluster
+	DriverCluster = "cluster"
+	// DriverSentinel Redis Sentinel
+	DriverSentinel = "sentinel"
+	// DriverMemory 进程内存, 适合本地开发和测试
+	DriverMemory = "memory"
 )
 
-// RedisClient Redis缓存客户端单例
-var RedisClient *redis.Client
+// NoExpiration 表示 key 没有设置过期时间
+const NoExpiration time.Duration = -1
+
+var (
+	// ErrNotFound 缓存键不存在
+	ErrNotFound = errors.New("cache: key not found")
+	// ErrNoKeysMatched 前缀下没有任何缓存键
+	ErrNoKeysMatched = errors.New("cache: no keys matched")
+)
+
+// Store 缓存后端接口, 所有 key 都会自动加上命名空间前缀
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	Del(ctx context.Context, key string) error
+	DelByPrefix(ctx context.Context, prefix string) error
+	// TTL 返回剩余过期时间, key 永不过期时返回 NoExpiration
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Incr 将 key 的整数值加一并保留原有的过期时间; key 不存在时从 0 开始, 并在 expiration 大于 0 时设置过期时间
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	// SAdd, SRem, SMembers 操作集合类型的 key, 集合为空时 key 被删除
+	SAdd(ctx context.Context, key string, members ...string) error
+	SRem(ctx context.Context, key string, members ...string) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+	// Scan 分页列出指定前缀的 key, 返回的 key 不带命名空间.
+	// cursor 为空表示从头开始, 返回的 next 为空表示遍历结束; 同一个 key 可能出现在多页中
+	Scan(ctx context.Context, prefix, cursor string, count int64) (keys []string, next string, err error)
+	// Namespace 返回当前后端使用的 key 命名空间
+	Namespace() string
+	// WithNamespace 返回共享同一个连接(内存后端为同一份数据)、使用另一个命名空间的后端,
+	// 用于把 IP 规则等数据与响应缓存隔开, 缓存管理接口扫描不到它们
+	WithNamespace(namespace string) Store
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Config 缓存后端配置
+type Config struct {
+	Driver     string   // redis, cluster, sentinel, memory
+	Addrs      []string // Redis 地址, cluster/sentinel 模式下为节点列表
+	Password   string
+	DB         int
+	MasterName string // sentinel 模式下的主节点名称
+	Namespace  string // key 命名空间, 例如 "openapphub:"
+}
 
-// Redis 在中间件中初始化redis链接
-func Redis() {
-	db, _ := strconv.ParseUint(os.Getenv("REDIS_DB"), 10, 64)
-	client := redis.NewClient(&redis.Options{
-		Addr:       os.Getenv("REDIS_ADDR"),
-		Password:   os.Getenv("REDIS_PW"),
-		DB:         int(db),
-		MaxRetries: 1,
-	})
+// New 根据配置创建缓存后端
+func New(conf Config) (Store, error) {
+	switch conf.Driver {
+	case DriverMemory:
+		return NewMemoryStore(conf.Namespace), nil
+	case DriverRedis, DriverCluster, DriverSentinel, "":
+		return NewRedisStore(conf)
+	default:
+		return nil, fmt.Errorf("unknown cache driver: %s", conf.Driver)
+	}
+}
 
-	_, err := client.Ping(context.Background()).Result()
+var defaultStore Store
 
+// Init 按配置初始化全局缓存后端
+func Init(conf Config) {
+	store, err := New(conf)
 	if err != nil {
-		util.Log().Panic("连接Redis不成功")
+		util.Log().Panic("连接缓存不成功: %v", err)
 	}
+	defaultStore = store
+}
 
-	RedisClient = client
+// Default 返回全局缓存后端
+func Default() Store {
+	if defaultStore == nil {
+		panic("Cache not initialized. Call cache.Init first.")
+	}
+	return defaultStore
 }
 
-const cachePrefix = ""
+// SetDefault 替换全局缓存后端, 主要用于测试
+func SetDefault(store Store) {
+	defaultStore = store
+}
 
 func Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	return RedisClient.Set(ctx, cachePrefix+key, value, expiration).Err()
+	return Default().Set(ctx, key, value, expiration)
 }
 
 func Exists(ctx context.Context, key string) (bool, error) {
-	n, err := RedisClient.Exists(ctx, cachePrefix+key).Result()
-	return n > 0, err
+	return Default().Exists(ctx, key)
 }
 
 func Expire(ctx context.Context, key string, expiration time.Duration) error {
-	return RedisClient.Expire(ctx, cachePrefix+key, expiration).Err()
+	return Default().Expire(ctx, key, expiration)
 }
 
 func Del(ctx context.Context, key string) error {
-	count, err := RedisClient.Del(ctx, cachePrefix+key).Result()
-	if err != nil {
-		return err
-	}
-
-	if count == 0 {
-		return fmt.Errorf("key %s does not exist", key)
-	}
-
-	return nil
+	return Default().Del(ctx, key)
 }
 
 func Get(ctx context.Context, key string) (string, error) {
-	return RedisClient.Get(ctx, cachePrefix+key).Result()
+	return Default().Get(ctx, key)
 }
 
-// New function to delete keys by prefix
+// DelByPrefix 删除指定前缀下的所有缓存键
 func DelByPrefix(ctx context.Context, prefix string) error {
-	var cursor uint64
-	var deletedKeys int
-	for {
-		var keys []string
-		var err error
-		keys, cursor, err = RedisClient.Scan(ctx, cursor, cachePrefix+prefix+"*", 100).Result()
-		if err != nil {
-			return err
-		}
-		if len(keys) > 0 {
-			if err := RedisClient.Del(ctx, keys...).Err(); err != nil {
-				return err
-			}
-			deletedKeys += len(keys)
-		}
-		if cursor == 0 {
-			break
-		}
-	}
-	if deletedKeys == 0 {
-		return fmt.Errorf("no keys found matching prefix: %s", prefix)
-	}
-	return nil
+	return Default().DelByPrefix(ctx, prefix)
 }
@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// 缓存条目信封格式:
+//
+//	magic(2 字节) | 版本(1 字节) | 编解码器(1 字节) | 压缩算法(1 字节) | 数据
+//
+// 没有 magic 的数据视为信封出现之前写入的裸 gob 数据(版本 0)
+const (
+	envelopeVersion    = 1
+	envelopeHeaderSize = 5
+)
+
+var envelopeMagic = [2]byte{0xCA, 0xCE}
+
+// 支持的编解码器
+const (
+	CodecGob     = "gob"
+	CodecMsgpack = "msgpack"
+	CodecJSON    = "json"
+)
+
+// 支持的压缩算法
+const (
+	CompressionNone   = "none"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy"
+)
+
+// maxDecodedSize 解压后的最大字节数, 防止损坏或恶意数据耗尽内存
+const maxDecodedSize = 64 << 20
+
+var (
+	// ErrUnsupportedVersion 缓存条目由更新版本的程序写入, 当前版本无法解析
+	ErrUnsupportedVersion = errors.New("cache: unsupported entry version")
+	// ErrCorruptEntry 缓存条目格式错误
+	ErrCorruptEntry = errors.New("cache: corrupt entry")
+)
+
+// Codec 缓存条目的序列化方式
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// codecs 和 compressions 中的 ID 会写入缓存条目, 只能追加不能修改
+var codecs = []struct {
+	id    byte
+	name  string
+	codec Codec
+}{
+	{1, CodecGob, gobCodec{}},
+	{2, CodecMsgpack, msgpackCodec{}},
+	{3, CodecJSON, jsonCodec{}},
+}
+
+var compressions = map[string]byte{
+	CompressionNone:   0,
+	CompressionZstd:   1,
+	CompressionSnappy: 2,
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// initZstd 创建共享的 zstd 编码器和解码器, 创建失败时每次调用都返回同一个错误
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			zstdErr = fmt.Errorf("create zstd encoder: %w", zstdErr)
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedSize))
+		if zstdErr != nil {
+			zstdErr = fmt.Errorf("create zstd decoder: %w", zstdErr)
+		}
+	})
+	return zstdErr
+}
+
+// EntryEncoder 将缓存条目编码为带版本的信封, 解码时兼容所有编解码器、压缩算法和旧版本
+type EntryEncoder struct {
+	Codec             string // gob, msgpack, json, 为空时使用 gob
+	Compression       string // none, zstd, snappy, 为空时不压缩
+	CompressThreshold int    // 序列化后的数据超过该字节数才压缩
+}
+
+// Validate 检查编码配置是否受支持, 使用 zstd 时同时创建编码器, 启动时即可发现错误
+func (e EntryEncoder) Validate() error {
+	if _, _, err := e.codec(); err != nil {
+		return err
+	}
+	id, err := e.compression()
+	if err != nil {
+		return err
+	}
+	if id == compressions[CompressionZstd] {
+		return initZstd()
+	}
+	return nil
+}
+
+func (e EntryEncoder) codec() (byte, Codec, error) {
+	name := e.Codec
+	if name == "" {
+		name = CodecGob
+	}
+	for _, c := range codecs {
+		if c.name == name {
+			return c.id, c.codec, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("unknown cache codec: %s", e.Codec)
+}
+
+func (e EntryEncoder) compression() (byte, error) {
+	name := e.Compression
+	if name == "" {
+		name = CompressionNone
+	}
+	id, ok := compressions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown cache compression: %s", e.Compression)
+	}
+	return id, nil
+}
+
+// Encode 序列化并按需压缩 v
+func (e EntryEncoder) Encode(v interface{}) ([]byte, error) {
+	codecID, codec, err := e.codec()
+	if err != nil {
+		return nil, err
+	}
+	compressionID, err := e.compression()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) <= e.CompressThreshold {
+		compressionID = compressions[CompressionNone]
+	}
+	switch compressionID {
+	case compressions[CompressionZstd]:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		payload = zstdEncoder.EncodeAll(payload, nil)
+	case compressions[CompressionSnappy]:
+		payload = snappy.Encode(nil, payload)
+	}
+
+	out := make([]byte, 0, envelopeHeaderSize+len(payload))
+	out = append(out, envelopeMagic[0], envelopeMagic[1], envelopeVersion, codecID, compressionID)
+	return append(out, payload...), nil
+}
+
+// Decode 解析任意版本的缓存条目
+func (e EntryEncoder) Decode(data []byte, v interface{}) error {
+	if len(data) < envelopeHeaderSize || data[0] != envelopeMagic[0] || data[1] != envelopeMagic[1] {
+		// 版本 0: 信封出现之前写入的裸 gob 数据
+		return gobCodec{}.Unmarshal(data, v)
+	}
+	if data[2] != envelopeVersion {
+		return ErrUnsupportedVersion
+	}
+
+	var codec Codec
+	for _, c := range codecs {
+		if c.id == data[3] {
+			codec = c.codec
+		}
+	}
+	if codec == nil {
+		return fmt.Errorf("%w: unknown codec %d", ErrCorruptEntry, data[3])
+	}
+
+	payload := data[envelopeHeaderSize:]
+	switch data[4] {
+	case compressions[CompressionNone]:
+	case compressions[CompressionZstd]:
+		if err := initZstd(); err != nil {
+			return err
+		}
+		decoded, err := zstdDecoder.DecodeAll(payload, nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCorruptEntry, err)
+		}
+		payload = decoded
+	case compressions[CompressionSnappy]:
+		n, err := snappy.DecodedLen(payload)
+		if err != nil || n > maxDecodedSize {
+			return fmt.Errorf("%w: invalid snappy block", ErrCorruptEntry)
+		}
+		decoded, err := snappy.Decode(nil, payload)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCorruptEntry, err)
+		}
+		payload = decoded
+	default:
+		return fmt.Errorf("%w: unknown compression %d", ErrCorruptEntry, data[4])
+	}
+
+	return codec.Unmarshal(payload, v)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	Status int
+	Header http.Header
+	Data   []byte
+}
+
+func TestEntryEncoderRoundTrip(t *testing.T) {
+	entry := testEntry{
+		Status: 200,
+		Header: http.Header{"X-Multi": {"a", "b"}},
+		Data:   []byte(strings.Repeat("payload", 100)),
+	}
+
+	for _, codec := range []string{CodecGob, CodecMsgpack, CodecJSON} {
+		for _, compression := range []string{CompressionNone, CompressionZstd, CompressionSnappy} {
+			encoder := EntryEncoder{Codec: codec, Compression: compression, CompressThreshold: 16}
+			data, err := encoder.Encode(entry)
+			if err != nil {
+				t.Fatalf("%s/%s: encode: %v", codec, compression, err)
+			}
+
+			// 解码不依赖当前的编码配置
+			var decoded testEntry
+			if err := (EntryEncoder{}).Decode(data, &decoded); err != nil {
+				t.Fatalf("%s/%s: decode: %v", codec, compression, err)
+			}
+			if !reflect.DeepEqual(decoded, entry) {
+				t.Errorf("%s/%s: got %+v, want %+v", codec, compression, decoded, entry)
+			}
+		}
+	}
+}
+
+func TestEntryEncoderThreshold(t *testing.T) {
+	encoder := EntryEncoder{Codec: CodecJSON, Compression: CompressionZstd, CompressThreshold: 1 << 20}
+	data, err := encoder.Encode(testEntry{Data: []byte("small")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[4] != compressions[CompressionNone] {
+		t.Errorf("entry below threshold was compressed with %d", data[4])
+	}
+}
+
+func TestEntryEncoderDecodesLegacyGob(t *testing.T) {
+	entry := testEntry{Status: 201, Data: []byte("legacy")}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded testEntry
+	if err := (EntryEncoder{}).Decode(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, entry) {
+		t.Errorf("got %+v, want %+v", decoded, entry)
+	}
+}
+
+func TestEntryEncoderRejectsUnknownVersion(t *testing.T) {
+	data := []byte{envelopeMagic[0], envelopeMagic[1], envelopeVersion + 1, 1, 0}
+	var decoded testEntry
+	if err := (EntryEncoder{}).Decode(data, &decoded); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("err = %v, want ErrUnsupportedVersion", err)
+	}
+}
@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	value     string
+	members   map[string]struct{} // 集合类型的 key
+	expiresAt time.Time           // 零值表示永不过期
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
+
+// MemoryStore 进程内缓存后端, 不依赖外部服务, 数据不跨实例共享
+type MemoryStore struct {
+	*memoryData
+	namespace string
+}
+
+// memoryData 同一个内存后端不同命名空间共享的数据
+type memoryData struct {
+	mu     sync.RWMutex
+	items  map[string]memoryItem
+	writes int
+}
+
+// purgeEvery 每写入多少次清理一次过期数据
+const purgeEvery = 1024
+
+// NewMemoryStore 创建进程内缓存后端
+func NewMemoryStore(namespace string) *MemoryStore {
+	return &MemoryStore{
+		memoryData: &memoryData{items: make(map[string]memoryItem)},
+		namespace:  namespace,
+	}
+}
+
+func (s *MemoryStore) Namespace() string {
+	return s.namespace
+}
+
+func (s *MemoryStore) WithNamespace(namespace string) Store {
+	return &MemoryStore{memoryData: s.memoryData, namespace: namespace}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (string, error) {
+	s.mu.RLock()
+	item, ok := s.items[s.namespace+key]
+	s.mu.RUnlock()
+	if !ok || item.expired(time.Now()) {
+		return "", ErrNotFound
+	}
+	return item.value, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, value interface{}, expiration time.Duration) error {
+	item := memoryItem{value: toString(value)}
+	if expiration > 0 {
+		item.expiresAt = time.Now().Add(expiration)
+	}
+
+	s.mu.Lock()
+	s.items[s.namespace+key] = item
+	s.writes++
+	if s.writes%purgeEvery == 0 {
+		s.purgeExpired(time.Now())
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// purgeExpired 清理过期数据, 调用方需持有写锁
+func (s *MemoryStore) purgeExpired(now time.Time) {
+	for key, item := range s.items {
+		if item.expired(now) {
+			delete(s.items, key)
+		}
+	}
+}
+
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.Get(ctx, key)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *MemoryStore) Expire(_ context.Context, key string, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[s.namespace+key]
+	if !ok || item.expired(time.Now()) {
+		return ErrNotFound
+	}
+	item.expiresAt = time.Now().Add(expiration)
+	s.items[s.namespace+key] = item
+	return nil
+}
+
+func (s *MemoryStore) Del(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[s.namespace+key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.items, s.namespace+key)
+	if item.expired(time.Now()) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MemoryStore) DelByPrefix(_ context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	deletedKeys := 0
+	for key, item := range s.items {
+		if !strings.HasPrefix(key, s.namespace+prefix) {
+			continue
+		}
+		delete(s.items, key)
+		if !item.expired(now) {
+			deletedKeys++
+		}
+	}
+	if deletedKeys == 0 {
+		return ErrNoKeysMatched
+	}
+	return nil
+}
+
+func (s *MemoryStore) TTL(_ context.Context, key string) (time.Duration, error) {
+	s.mu.RLock()
+	item, ok := s.items[s.namespace+key]
+	s.mu.RUnlock()
+
+	now := time.Now()
+	if !ok || item.expired(now) {
+		return 0, ErrNotFound
+	}
+	if item.expiresAt.IsZero() {
+		return NoExpiration, nil
+	}
+	return item.expiresAt.Sub(now), nil
+}
+
+func (s *MemoryStore) Incr(_ context.Context, key string, expiration time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	item, ok := s.items[s.namespace+key]
+	if !ok || item.expired(now) {
+		item = memoryItem{value: "0"}
+		if expiration > 0 {
+			item.expiresAt = now.Add(expiration)
+		}
+	}
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value is not an integer: %w", err)
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	s.items[s.namespace+key] = item
+	return n, nil
+}
+
+func (s *MemoryStore) SAdd(_ context.Context, key string, members ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[s.namespace+key]
+	if !ok || item.expired(time.Now()) {
+		item = memoryItem{}
+	}
+	if item.members == nil {
+		item.members = make(map[string]struct{}, len(members))
+	}
+	for _, member := range members {
+		item.members[member] = struct{}{}
+	}
+	s.items[s.namespace+key] = item
+	return nil
+}
+
+func (s *MemoryStore) SRem(_ context.Context, key string, members ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[s.namespace+key]
+	if !ok || item.expired(time.Now()) {
+		return nil
+	}
+	for _, member := range members {
+		delete(item.members, member)
+	}
+	if len(item.members) == 0 {
+		delete(s.items, s.namespace+key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SMembers(_ context.Context, key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[s.namespace+key]
+	if !ok || item.expired(time.Now()) {
+		return []string{}, nil
+	}
+	members := make([]string, 0, len(item.members))
+	for member := range item.members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// Scan 的 cursor 为上一页最后一个 key, 按字典序遍历
+func (s *MemoryStore) Scan(_ context.Context, prefix, cursor string, count int64) ([]string, string, error) {
+	s.mu.RLock()
+	now := time.Now()
+	var matched []string
+	for key, item := range s.items {
+		// 共享数据的其它命名空间的 key 不属于这个 store
+		key, ok := strings.CutPrefix(key, s.namespace)
+		if ok && strings.HasPrefix(key, prefix) && key > cursor && !item.expired(now) {
+			matched = append(matched, key)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(matched)
+	if int64(len(matched)) <= count {
+		return matched, "", nil
+	}
+	keys := matched[:count]
+	return keys, keys[len(keys)-1], nil
+}
+
+func (s *MemoryStore) Ping(_ context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	s.items = make(map[string]memoryItem)
+	s.mu.Unlock()
+	return nil
+}
+
+// toString 与 go-redis 的参数编码保持一致, 保证两种后端读出的值相同
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
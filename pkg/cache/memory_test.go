@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore("test:")
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get missing: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Set(ctx, "k", 42, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := store.Get(ctx, "k"); err != nil || v != "42" {
+		t.Errorf("get = %q, %v", v, err)
+	}
+	if ttl, err := store.TTL(ctx, "k"); err != nil || ttl != NoExpiration {
+		t.Errorf("ttl = %v, %v, want NoExpiration", ttl, err)
+	}
+
+	// 过期后读取不到, Incr 从 0 开始并设置新的过期时间
+	if err := store.Set(ctx, "short", "1", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Exists(ctx, "short"); !ok {
+		t.Error("short expired too early")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := store.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get expired: err = %v, want ErrNotFound", err)
+	}
+	if n, err := store.Incr(ctx, "short", time.Minute); err != nil || n != 1 {
+		t.Errorf("incr expired = %d, %v, want 1", n, err)
+	}
+	if ttl, _ := store.TTL(ctx, "short"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl of created counter = %v, want at most 1m", ttl)
+	}
+
+	// Incr 保留原有的过期时间
+	if err := store.Set(ctx, "hits", 5, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Incr(ctx, "hits", time.Hour); err != nil || n != 6 {
+		t.Errorf("incr = %d, %v, want 6", n, err)
+	}
+	if ttl, _ := store.TTL(ctx, "hits"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl after incr = %v", ttl)
+	}
+	if _, err := store.Incr(ctx, "k2", 0); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Set(ctx, "text", "abc", 0)
+	if _, err := store.Incr(ctx, "text", 0); err == nil {
+		t.Error("incr on non-integer value should fail")
+	}
+
+	if err := store.Del(ctx, "k"); err != nil {
+		t.Errorf("del: %v", err)
+	}
+	if err := store.Del(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("del twice: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreScan(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore("test:")
+	var want []string
+	for i := 0; i < 7; i++ {
+		key := fmt.Sprintf("page:%d", i)
+		want = append(want, key)
+		_ = store.Set(ctx, key, i, 0)
+	}
+	_ = store.Set(ctx, "other", 1, 0)
+	_ = store.Set(ctx, "page:expired", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var got []string
+	cursor, pages := "", 0
+	for {
+		keys, next, err := store.Scan(ctx, "page:", cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) > 3 {
+			t.Errorf("page has %d keys, want at most 3", len(keys))
+		}
+		got = append(got, keys...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if pages != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("scan = %v in %d pages, want %v in 3 pages", got, pages, want)
+	}
+
+	if err := store.DelByPrefix(ctx, "page:"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _, _ := store.Scan(ctx, "", "", 10); !reflect.DeepEqual(keys, []string{"other"}) {
+		t.Errorf("keys after DelByPrefix = %v", keys)
+	}
+	if err := store.DelByPrefix(ctx, "page:"); !errors.Is(err, ErrNoKeysMatched) {
+		t.Errorf("DelByPrefix again: err = %v, want ErrNoKeysMatched", err)
+	}
+
+	// 共享数据的其它命名空间的 key 不会出现在结果中
+	_ = store.WithNamespace("rules:").Set(ctx, "other", 1, 0)
+	if keys, _, _ := store.Scan(ctx, "", "", 10); !reflect.DeepEqual(keys, []string{"other"}) {
+		t.Errorf("keys with another namespace = %v", keys)
+	}
+}
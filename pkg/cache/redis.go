@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore 基于 Redis 的缓存后端, 支持单机、Cluster 和 Sentinel
+type RedisStore struct {
+	client    redis.UniversalClient
+	namespace string
+}
+
+// NewRedisStore 创建 Redis 缓存后端并检查连接
+func NewRedisStore(conf Config) (*RedisStore, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:      conf.Addrs,
+		Password:   conf.Password,
+		DB:         conf.DB,
+		MaxRetries: 1,
+	}
+
+	var client redis.UniversalClient
+	switch conf.Driver {
+	case DriverCluster:
+		client = redis.NewClusterClient(opts.Cluster())
+	case DriverSentinel:
+		opts.MasterName = conf.MasterName
+		client = redis.NewFailoverClient(opts.Failover())
+	default:
+		client = redis.NewClient(opts.Simple())
+	}
+
+	store := NewRedisStoreWithClient(client, conf.Namespace)
+	if err := store.Ping(context.Background()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewRedisStoreWithClient 使用已有的 Redis 客户端创建缓存后端
+func NewRedisStoreWithClient(client redis.UniversalClient, namespace string) *RedisStore {
+	return &RedisStore{client: client, namespace: namespace}
+}
+
+// Client 返回底层 Redis 客户端, 供限流等需要原生命令的组件使用
+func (s *RedisStore) Client() redis.UniversalClient {
+	return s.client
+}
+
+func (s *RedisStore) Namespace() string {
+	return s.namespace
+}
+
+func (s *RedisStore) WithNamespace(namespace string) Store {
+	return NewRedisStoreWithClient(s.client, namespace)
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, s.namespace+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return s.client.Set(ctx, s.namespace+key, value, expiration).Err()
+}
+
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.namespace+key).Result()
+	return n > 0, err
+}
+
+func (s *RedisStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	ok, err := s.client.Expire(ctx, s.namespace+key, expiration).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) Del(ctx context.Context, key string) error {
+	count, err := s.client.Del(ctx, s.namespace+key).Result()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) DelByPrefix(ctx context.Context, prefix string) error {
+	// cluster 模式下各节点并发扫描, 计数需要原子操作
+	var deletedKeys atomic.Int64
+	err := s.scan(ctx, matchPrefix(s.namespace+prefix), func(client redis.UniversalClient, keys []string) error {
+		// cluster 模式下 key 可能分布在不同 slot, 逐个删除避免 CROSSSLOT 错误
+		for _, key := range keys {
+			if err := client.Del(ctx, key).Err(); err != nil {
+				return err
+			}
+		}
+		deletedKeys.Add(int64(len(keys)))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deletedKeys.Load() == 0 {
+		return ErrNoKeysMatched
+	}
+	return nil
+}
+
+func (s *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.TTL(ctx, s.namespace+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	switch ttl {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return NoExpiration, nil
+	}
+	return ttl, nil
+}
+
+// incrScript 自增并在创建 key 时设置过期时间, 两步在同一个脚本中执行, 不会留下没有过期时间的 key
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (s *RedisStore) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.client, []string{s.namespace + key}, expiration.Milliseconds()).Int64()
+}
+
+func (s *RedisStore) SAdd(ctx context.Context, key string, members ...string) error {
+	return s.client.SAdd(ctx, s.namespace+key, toInterfaces(members)...).Err()
+}
+
+func (s *RedisStore) SRem(ctx context.Context, key string, members ...string) error {
+	return s.client.SRem(ctx, s.namespace+key, toInterfaces(members)...).Err()
+}
+
+func (s *RedisStore) SMembers(ctx context.Context, key string) ([]string, error) {
+	return s.client.SMembers(ctx, s.namespace+key).Result()
+}
+
+// globEscaper 转义 SCAN MATCH 的通配符
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// matchPrefix 返回只匹配以 prefix 开头的 key 的 MATCH 模式, prefix 中的通配符按字面匹配
+func matchPrefix(prefix string) string {
+	return globEscaper.Replace(prefix) + "*"
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// Scan 的 cursor 格式为 "<节点序号>:<SCAN 游标>", cluster 模式下按地址顺序逐个遍历 master 节点
+func (s *RedisStore) Scan(ctx context.Context, prefix, cursor string, count int64) ([]string, string, error) {
+	nodes, err := s.nodes(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	node, position := 0, uint64(0)
+	if cursor != "" {
+		if _, err := fmt.Sscanf(cursor, "%d:%d", &node, &position); err != nil || node < 0 {
+			return nil, "", fmt.Errorf("invalid cursor: %s", cursor)
+		}
+	}
+
+	var keys []string
+	for node < len(nodes) && int64(len(keys)) < count {
+		page, next, err := nodes[node].Scan(ctx, position, matchPrefix(s.namespace+prefix), count).Result()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, key := range page {
+			keys = append(keys, strings.TrimPrefix(key, s.namespace))
+		}
+		if next == 0 {
+			node, position = node+1, 0
+		} else {
+			position = next
+		}
+	}
+
+	if node >= len(nodes) {
+		return keys, "", nil
+	}
+	return keys, fmt.Sprintf("%d:%d", node, position), nil
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// nodes 返回需要遍历的节点, cluster 模式下为按地址排序的全部 master 节点
+func (s *RedisStore) nodes(ctx context.Context) ([]redis.UniversalClient, error) {
+	cluster, ok := s.client.(*redis.ClusterClient)
+	if !ok {
+		return []redis.UniversalClient{s.client}, nil
+	}
+
+	var mu sync.Mutex
+	var masters []*redis.Client
+	err := cluster.ForEachMaster(ctx, func(_ context.Context, node *redis.Client) error {
+		mu.Lock()
+		masters = append(masters, node)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(masters, func(i, j int) bool {
+		return masters[i].Options().Addr < masters[j].Options().Addr
+	})
+
+	nodes := make([]redis.UniversalClient, len(masters))
+	for i, master := range masters {
+		nodes[i] = master
+	}
+	return nodes, nil
+}
+
+// scan 遍历匹配 pattern 的 key, cluster 模式下会遍历每个 master 节点
+func (s *RedisStore) scan(ctx context.Context, pattern string, fn func(client redis.UniversalClient, keys []string) error) error {
+	scanNode := func(ctx context.Context, client redis.UniversalClient) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, pattern, 100).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(client, keys); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scanNode(ctx, node)
+		})
+	}
+	return scanNode(ctx, s.client)
+}
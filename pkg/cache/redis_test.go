@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	store := NewRedisStoreWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	t.Cleanup(func() { store.Close() })
+
+	if err := store.Set(ctx, "k", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("test:k") {
+		t.Error("key is not stored under the namespace")
+	}
+	if _, err := store.Incr(ctx, "k", 0); err == nil {
+		t.Error("incr on non-integer value should fail")
+	}
+	// 新建的计数器带有过期时间, 已有的计数器保留原来的过期时间
+	if n, err := store.Incr(ctx, "hits", time.Minute); err != nil || n != 1 {
+		t.Errorf("incr = %d, %v, want 1", n, err)
+	}
+	if n, err := store.Incr(ctx, "hits", time.Hour); err != nil || n != 2 {
+		t.Errorf("incr = %d, %v, want 2", n, err)
+	}
+	if ttl := server.TTL("test:hits"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl of counter = %v, want at most 1m", ttl)
+	}
+	server.FastForward(2 * time.Minute)
+	if _, err := store.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get expired: err = %v, want ErrNotFound", err)
+	}
+	if _, err := store.TTL(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ttl expired: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Del(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("del missing: err = %v, want ErrNotFound", err)
+	}
+
+	// 前缀中的通配符按字面匹配
+	for _, key := range []string{"a*b:1", "axb:1", `a\b:1`} {
+		_ = store.Set(ctx, key, 1, 0)
+	}
+	if keys, _, err := store.Scan(ctx, "a*", "", 10); err != nil || !slices.Equal(keys, []string{"a*b:1"}) {
+		t.Errorf("scan a* = %v, %v", keys, err)
+	}
+	if err := store.DelByPrefix(ctx, "a*"); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("test:axb:1") || !server.Exists(`test:a\b:1`) || server.Exists("test:a*b:1") {
+		t.Errorf("DelByPrefix a* left keys %v", server.Keys())
+	}
+}
+
+// TestRedisStoreScanNodes 两个节点各负责一半 slot, 游标依次遍历两个节点
+func TestRedisStoreScanNodes(t *testing.T) {
+	ctx := context.Background()
+	servers := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Addr() < servers[j].Addr() })
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: servers[0].Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: servers[1].Addr()}}},
+			}, nil
+		},
+	})
+	store := NewRedisStoreWithClient(client, "test:")
+	t.Cleanup(func() { store.Close() })
+
+	// 直接写入节点, 不依赖 key 的 slot 分布
+	want := map[string]bool{}
+	for i, server := range servers {
+		for j := 0; j < 5; j++ {
+			key := fmt.Sprintf("page:%d:%d", i, j)
+			want[key] = true
+			server.Set("test:"+key, "1")
+		}
+		server.Set("test:other", "1")
+	}
+
+	got := map[string]bool{}
+	cursorFormat := regexp.MustCompile(`^\d+:\d+$`)
+	cursor, nodesSeen := "", map[string]bool{}
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatal("scan did not finish")
+		}
+		keys, next, err := store.Scan(ctx, "page:", cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range keys {
+			got[key] = true
+		}
+		if next == "" {
+			break
+		}
+		if !cursorFormat.MatchString(next) {
+			t.Fatalf("cursor %q is not <node>:<position>", next)
+		}
+		nodesSeen[next[:1]] = true
+		cursor = next
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("scan = %v, want %v", got, want)
+	}
+	if !nodesSeen["1"] {
+		t.Errorf("cursor never moved to the second node: %v", nodesSeen)
+	}
+
+	if _, _, err := store.Scan(ctx, "page:", "bad", 2); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
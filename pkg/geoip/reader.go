@@ -0,0 +1,334 @@
+// Package geoip 读取 MaxMind DB(mmdb)格式的本地数据库, 只实现按 IP 查询国家代码所需的部分
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// ErrInvalidDatabase 数据库文件格式错误
+var ErrInvalidDatabase = errors.New("geoip: invalid database")
+
+// metadataMarker 元数据段的起始标记, 位于文件末尾 128KB 以内
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator 搜索树与数据段之间的 16 个零字节
+const dataSectionSeparator = 16
+
+// Reader 内存中的 mmdb 数据库, 可以并发查询
+type Reader struct {
+	buf        []byte
+	data       []byte // 数据段
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint // IPv6 数据库中 IPv4 子树的起始节点
+}
+
+// Open 读取 mmdb 数据库文件
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(buf)
+}
+
+// FromBytes 从内存中的数据创建 Reader
+func FromBytes(buf []byte) (*Reader, error) {
+	start := len(buf) - 128*1024
+	if start < 0 {
+		start = 0
+	}
+	index := bytes.LastIndex(buf[start:], metadataMarker)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+	metaStart := start + index + len(metadataMarker)
+	value, _, err := decoder{buf: buf[metaStart:]}.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	r := &Reader{buf: buf}
+	r.nodeCount = uint(toUint(meta["node_count"]))
+	r.recordSize = uint(toUint(meta["record_size"]))
+	r.ipVersion = uint(toUint(meta["ip_version"]))
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.recordSize)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(start+index) {
+		return nil, fmt.Errorf("%w: search tree exceeds file size", ErrInvalidDatabase)
+	}
+	r.data = buf[treeSize+dataSectionSeparator : start+index]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup 返回 IP 对应的数据记录, 没有记录时返回 nil
+func (r *Reader) Lookup(ip netip.Addr) (interface{}, error) {
+	ip = ip.Unmap()
+	node := uint(0)
+	var bits []byte
+	switch {
+	case ip.Is4() && r.ipVersion == 6:
+		node = r.ipv4Start
+		b := ip.As4()
+		bits = b[:]
+	case ip.Is4():
+		b := ip.As4()
+		bits = b[:]
+	case r.ipVersion == 6:
+		b := ip.As16()
+		bits = b[:]
+	default:
+		// IPv4 数据库无法查询 IPv6 地址
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := (bits[i/8] >> (7 - i%8)) & 1
+		node = r.readNode(node, uint(bit))
+	}
+	if node <= r.nodeCount {
+		return nil, nil
+	}
+	offset := node - r.nodeCount - dataSectionSeparator
+	value, _, err := decoder{buf: r.data}.decode(offset)
+	return value, err
+}
+
+// Country 返回 IP 所属国家的 ISO 3166-1 代码, 查不到时返回空字符串
+func (r *Reader) Country(ip netip.Addr) (string, error) {
+	record, err := r.Lookup(ip)
+	if err != nil || record == nil {
+		return "", err
+	}
+	fields, _ := record.(map[string]interface{})
+	for _, key := range []string{"country", "registered_country"} {
+		country, _ := fields[key].(map[string]interface{})
+		if code, ok := country["iso_code"].(string); ok {
+			return code, nil
+		}
+	}
+	return "", nil
+}
+
+// readNode 读取节点的左(0)或右(1)记录
+func (r *Reader) readNode(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(r.buf[node*8+bit*4:]))
+	}
+}
+
+// 数据段的字段类型
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+type decoder struct {
+	buf []byte
+}
+
+// maxDepth 嵌套的 map/array/指针层数上限, 防止损坏的数据库造成无限递归
+const maxDepth = 32
+
+// decode 解析 offset 处的值, 返回值和下一个字段的偏移
+func (d decoder) decode(offset uint) (interface{}, uint, error) {
+	return d.decodeDepth(offset, 0)
+}
+
+func (d decoder) decodeDepth(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("%w: data nested too deeply", ErrInvalidDatabase)
+	}
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("%w: offset out of range", ErrInvalidDatabase)
+	}
+	ctrl := d.buf[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+	if kind == typePointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decodeDepth(pointer, depth+1)
+		return value, next, err
+	}
+	if kind == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("%w: truncated type", ErrInvalidDatabase)
+		}
+		kind = 7 + uint(d.buf[offset])
+		offset++
+	}
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{})
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key is not a string", ErrInvalidDatabase)
+			}
+			value, next, err := d.decodeDepth(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		var a []interface{}
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("%w: value out of range", ErrInvalidDatabase)
+	}
+	raw := d.buf[offset : offset+size]
+	next := offset + size
+	switch kind {
+	case typeString:
+		return string(raw), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: invalid double size", ErrInvalidDatabase)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: invalid float size", ErrInvalidDatabase)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		var n uint64
+		for _, b := range raw {
+			n = n<<8 | uint64(b)
+		}
+		return n, next, nil
+	case typeInt32:
+		var n uint32
+		for _, b := range raw {
+			n = n<<8 | uint32(b)
+		}
+		return int64(int32(n)), next, nil
+	case typeBytes, typeUint128:
+		return raw, next, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unknown type %d", ErrInvalidDatabase, kind)
+}
+
+func (d decoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1F)
+	if size < 29 {
+		return size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("%w: truncated size", ErrInvalidDatabase)
+	}
+	var extra uint
+	for _, b := range d.buf[offset : offset+n] {
+		extra = extra<<8 | uint(b)
+	}
+	switch size {
+	case 29:
+		size = 29 + extra
+	case 30:
+		size = 285 + extra
+	default:
+		size = 65821 + extra
+	}
+	return size, offset + n, nil
+}
+
+func (d decoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("%w: truncated pointer", ErrInvalidDatabase)
+	}
+	var pointer uint
+	if n < 4 {
+		pointer = uint(ctrl & 0x7)
+	}
+	for _, b := range d.buf[offset : offset+n] {
+		pointer = pointer<<8 | uint(b)
+	}
+	switch n {
+	case 2:
+		pointer += 2048
+	case 3:
+		pointer += 526336
+	}
+	return pointer, offset + n, nil
+}
+
+func toUint(value interface{}) uint64 {
+	n, _ := value.(uint64)
+	return n
+}
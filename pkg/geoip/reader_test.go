@@ -0,0 +1,63 @@
+package geoip
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func str(s string) []byte {
+	return append([]byte{byte(2<<5 | len(s))}, s...)
+}
+
+// testDatabase 构造一个只有一个节点的 IPv4 数据库: 0.0.0.0/1 属于 US, 其余地址没有记录
+func testDatabase() []byte {
+	var buf bytes.Buffer
+	// 节点 0: 左记录指向数据段偏移 0 (1 + 16 + 0), 右记录等于 node_count 表示没有数据
+	buf.Write([]byte{0x00, 0x00, 0x11, 0x00, 0x00, 0x01})
+	buf.Write(make([]byte, dataSectionSeparator))
+	// {"country": {"iso_code": "US"}}
+	buf.WriteByte(7<<5 | 1)
+	buf.Write(str("country"))
+	buf.WriteByte(7<<5 | 1)
+	buf.Write(str("iso_code"))
+	buf.Write(str("US"))
+
+	buf.Write(metadataMarker)
+	buf.WriteByte(7<<5 | 3)
+	buf.Write(str("node_count"))
+	buf.Write([]byte{6<<5 | 1, 1})
+	buf.Write(str("record_size"))
+	buf.Write([]byte{5<<5 | 1, 24})
+	buf.Write(str("ip_version"))
+	buf.Write([]byte{5<<5 | 1, 4})
+	return buf.Bytes()
+}
+
+func TestCountry(t *testing.T) {
+	r, err := FromBytes(testDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"8.8.8.8":     "US",
+		"127.0.0.1":   "US",
+		"203.0.113.1": "",
+		"2001:db8::1": "",
+	}
+	for ip, want := range tests {
+		got, err := r.Country(netip.MustParseAddr(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Country(%s) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestInvalidDatabase(t *testing.T) {
+	if _, err := FromBytes([]byte("not a database")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
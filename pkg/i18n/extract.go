@@ -0,0 +1,171 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// importPathSuffix 用于识别导入本包的文件
+const importPathSuffix = "/pkg/i18n"
+
+// Usage 代码中使用的消息 key
+type Usage struct {
+	Keys     map[string][]token.Position // 字面量 key, 例如 T(c, "User.NotLoggedIn")
+	Prefixes map[string][]token.Position // 拼接出的 key 的固定前缀, 例如 T(c, "Field."+name) 中的 Field.
+	Dynamic  []token.Position            // 无法确定 key 的调用
+}
+
+// Extract 扫描 root 下所有 Go 文件(不包括测试和 vendor)中 i18n.T、i18n.Translate 的调用
+func Extract(root string) (*Usage, error) {
+	u := &Usage{
+		Keys:     make(map[string][]token.Position),
+		Prefixes: make(map[string][]token.Position),
+	}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		u.scan(fset, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// scan 记录一个文件中的调用
+func (u *Usage) scan(fset *token.FileSet, file *ast.File) {
+	pkgName := ""
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if !strings.HasSuffix(path, importPathSuffix) {
+			continue
+		}
+		pkgName = "i18n"
+		if imp.Name != nil {
+			pkgName = imp.Name.Name
+		}
+	}
+	if pkgName == "" {
+		return
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "T" && sel.Sel.Name != "Translate") {
+			return true
+		}
+		if ident, ok := sel.X.(*ast.Ident); !ok || ident.Name != pkgName {
+			return true
+		}
+		pos := fset.Position(call.Pos())
+		key, prefix := keyOf(call.Args[1])
+		switch {
+		case key != "":
+			u.Keys[key] = append(u.Keys[key], pos)
+		case prefix != "":
+			u.Prefixes[prefix] = append(u.Prefixes[prefix], pos)
+		default:
+			u.Dynamic = append(u.Dynamic, pos)
+		}
+		return true
+	})
+}
+
+// keyOf 返回字面量 key, 或者 "prefix"+x、fmt.Sprintf("prefix%s", x) 中的前缀
+func keyOf(expr ast.Expr) (key, prefix string) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			key, _ = strconv.Unquote(e.Value)
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			if left, _ := keyOf(e.X); left != "" {
+				return "", left
+			}
+			_, prefix = keyOf(e.X)
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" && len(e.Args) > 0 {
+			if format, _ := keyOf(e.Args[0]); format != "" {
+				if i := strings.IndexByte(format, '%'); i >= 0 {
+					return "", format[:i]
+				}
+				return format, ""
+			}
+		}
+	}
+	return key, prefix
+}
+
+// Used 判断 key 是否被代码使用, 包括匹配拼接前缀的 key
+func (u *Usage) Used(key string) bool {
+	if _, ok := u.Keys[key]; ok {
+		return true
+	}
+	for prefix := range u.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report 一种语言的检查结果
+type Report struct {
+	Locale  string
+	Missing []string // 代码中使用但目录中没有的 key
+	Unused  []string // 目录中有但代码中没有使用的 key
+}
+
+// Check 按语言检查消息目录, 结果按语言排序
+func (u *Usage) Check(b *Bundle) []Report {
+	used := make([]string, 0, len(u.Keys))
+	for key := range u.Keys {
+		used = append(used, key)
+	}
+	sort.Strings(used)
+
+	reports := make([]Report, 0, len(b.Locales()))
+	for _, locale := range b.Locales() {
+		r := Report{Locale: locale}
+		for _, key := range used {
+			if _, ok := b.catalogs[locale][key]; !ok {
+				r.Missing = append(r.Missing, key)
+			}
+		}
+		for _, key := range b.Keys(locale) {
+			if !u.Used(key) {
+				r.Unused = append(r.Unused, key)
+			}
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
@@ -0,0 +1,313 @@
+// Package i18n 加载多语言消息目录, 按 Accept-Language 或用户偏好选择语言.
+// 目录文件为 YAML, 文件名即语言, 例如 zh-cn.yaml、en-us.yaml; 嵌套的 key 用 . 连接, 例如 User.NotLoggedIn.
+// 消息使用 ICU MessageFormat, 支持参数、复数、select 以及数字和日期格式化, 见 message.go
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultLocale 找不到匹配的语言时使用的语言
+const DefaultLocale = "zh-CN"
+
+// ContextKey 当前请求的语言在 gin.Context 中的 key
+const ContextKey = "locale"
+
+// Params 消息中 {name} 占位符的值
+type Params map[string]interface{}
+
+// Bundle 所有语言的消息目录
+type Bundle struct {
+	catalogs map[string]map[string]string
+	messages map[string]map[string]message // 解析后的消息, 与 catalogs 一一对应
+	locales  []string                      // 已加载的语言, 按名称排序
+}
+
+var bundle atomic.Pointer[Bundle]
+
+// LoadDir 读取目录下所有 .yaml/.yml 文件并替换当前的消息目录
+func LoadDir(dir string) error {
+	b, err := ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	bundle.Store(b)
+	return nil
+}
+
+// SetBundle 替换当前的消息目录, 主要用于测试
+func SetBundle(b *Bundle) {
+	bundle.Store(b)
+}
+
+// ReadDir 读取目录下所有 .yaml/.yml 文件
+func ReadDir(dir string) (*Bundle, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	b := NewBundle()
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Add(strings.TrimSuffix(entry.Name(), ext), data); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	if len(b.catalogs) == 0 {
+		return nil, fmt.Errorf("no locale files found in %s", dir)
+	}
+	return b, nil
+}
+
+// NewBundle 创建空的消息目录
+func NewBundle() *Bundle {
+	return &Bundle{
+		catalogs: make(map[string]map[string]string),
+		messages: make(map[string]map[string]message),
+	}
+}
+
+// Add 添加一种语言的 YAML 消息目录, 同一种语言多次添加时合并. 消息格式错误时返回包含 key 的错误
+func (b *Bundle) Add(locale string, data []byte) error {
+	var tree map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return err
+	}
+	added := make(map[string]string)
+	flatten(added, "", tree)
+	parsed := make(map[string]message, len(added))
+	for key, source := range added {
+		m, err := parseMessage(source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		parsed[key] = m
+	}
+
+	locale = Canonical(locale)
+	if b.catalogs[locale] == nil {
+		b.catalogs[locale] = make(map[string]string)
+		b.messages[locale] = make(map[string]message)
+		b.locales = append(b.locales, locale)
+		sort.Strings(b.locales)
+	}
+	for key, source := range added {
+		b.catalogs[locale][key] = source
+		b.messages[locale][key] = parsed[key]
+	}
+	return nil
+}
+
+func flatten(catalog map[string]string, prefix string, tree map[interface{}]interface{}) {
+	for k, v := range tree {
+		key := prefix + fmt.Sprint(k)
+		switch v := v.(type) {
+		case map[interface{}]interface{}:
+			flatten(catalog, key+".", v)
+		case nil:
+		default:
+			catalog[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// Locales 返回已加载的语言
+func (b *Bundle) Locales() []string {
+	return b.locales
+}
+
+// Lookup 按回退链查找消息, 返回未格式化的消息和是否找到
+func (b *Bundle) Lookup(locale, key string) (string, bool) {
+	for _, candidate := range b.chain(locale) {
+		if source, ok := b.catalogs[candidate][key]; ok {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// Keys 返回语言自身定义的 key, 不包括回退的 key, 按名称排序
+func (b *Bundle) Keys(locale string) []string {
+	catalog := b.catalogs[Canonical(locale)]
+	keys := make([]string, 0, len(catalog))
+	for key := range catalog {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Arguments 返回语言中 key 对应的消息引用的参数名, 按名称排序
+func (b *Bundle) Arguments(locale, key string) []string {
+	names := make(map[string]struct{})
+	b.messages[Canonical(locale)][key].arguments(names)
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// message 按回退链查找解析后的消息, 同时返回消息所属的语言
+func (b *Bundle) message(locale, key string) (message, string, bool) {
+	for _, candidate := range b.chain(locale) {
+		if m, ok := b.messages[candidate][key]; ok {
+			return m, candidate, true
+		}
+	}
+	return nil, "", false
+}
+
+// chain 返回语言的回退链: 完整语言 -> 语言代码 -> 同语言的其它地区 -> DefaultLocale.
+// 例如 zh-TW -> zh -> zh-CN, en-GB -> en -> en-US -> zh-CN
+func (b *Bundle) chain(locale string) []string {
+	locale = Canonical(locale)
+	language, _, _ := strings.Cut(locale, "-")
+	chain := []string{locale, language}
+	for _, candidate := range b.locales {
+		if strings.HasPrefix(candidate, language+"-") {
+			chain = append(chain, candidate)
+		}
+	}
+	return append(chain, DefaultLocale)
+}
+
+// Match 返回与 locale 最接近的已加载语言, 没有同语言的目录时返回空字符串
+func (b *Bundle) Match(locale string) string {
+	chain := b.chain(locale)
+	for _, candidate := range chain[:len(chain)-1] {
+		if _, ok := b.catalogs[candidate]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Match 返回与 locale 最接近的已加载语言, 不支持该语言时返回空字符串
+func Match(locale string) string {
+	b := bundle.Load()
+	if b == nil || locale == "" {
+		return ""
+	}
+	return b.Match(locale)
+}
+
+// Canonical 规范化语言标签, 例如 zh_cn、ZH-cn 都转换为 zh-CN
+func Canonical(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		} else if len(parts[i]) == 4 {
+			// 文字代码, 例如 zh-Hant
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Negotiate 选择请求使用的语言: 用户偏好优先, 其次按 Accept-Language 的权重, 都不匹配时使用 DefaultLocale
+func Negotiate(preferred, acceptLanguage string) string {
+	b := bundle.Load()
+	if b == nil {
+		return DefaultLocale
+	}
+	if preferred != "" {
+		if locale := b.Match(preferred); locale != "" {
+			return locale
+		}
+	}
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if locale := b.Match(tag); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// parseAcceptLanguage 按权重从高到低返回语言标签, 忽略 q=0 的语言
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name == "q" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	result := make([]string, len(tags))
+	for i, tag := range tags {
+		result[i] = tag.tag
+	}
+	return result
+}
+
+// Locale 返回请求的语言, ctx 可以是 *gin.Context 或带有 ContextKey 的 context.Context
+func Locale(ctx context.Context) string {
+	if ctx != nil {
+		if locale, ok := ctx.Value(ContextKey).(string); ok && locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// T 按请求的语言翻译消息, 找不到时返回 key
+func T(ctx context.Context, key string, params ...Params) string {
+	return Translate(Locale(ctx), key, params...)
+}
+
+// Translate 按指定语言翻译并格式化消息, 找不到时返回 key. 没有提供值的 {name} 参数保持原样
+func Translate(locale, key string, params ...Params) string {
+	b := bundle.Load()
+	if b == nil {
+		return key
+	}
+	m, found, ok := b.message(locale, key)
+	if !ok {
+		return key
+	}
+	var p Params
+	if len(params) > 0 {
+		p = params[0]
+	}
+	// 回退到其它语言的消息时按该语言的规则格式化, 避免复数分支与语言不匹配
+	return m.format(found, p)
+}
@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b := NewBundle()
+	if err := b.Add("zh-cn", []byte("User:\n  NotLoggedIn: 未登录\n  Hello: 你好 {name}\nOnly: 只有中文\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add("en-us", []byte("User:\n  NotLoggedIn: Not logged in\n  Hello: Hello {name}, {unknown}\n")); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNegotiate(t *testing.T) {
+	SetBundle(testBundle(t))
+	defer SetBundle(nil)
+
+	tests := []struct {
+		preferred, accept, want string
+	}{
+		{"", "", "zh-CN"},
+		{"", "en-US,en;q=0.9", "en-US"},
+		{"", "fr-FR, en-GB;q=0.8, zh;q=0.9", "zh-CN"},
+		{"", "zh;q=0, en-gb", "en-US"},
+		{"", "fr, *", "zh-CN"},
+		{"en", "zh-CN", "en-US"},
+		{"de-DE", "en", "en-US"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.preferred, tt.accept); got != tt.want {
+			t.Errorf("Negotiate(%q, %q) = %q, want %q", tt.preferred, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	SetBundle(testBundle(t))
+	defer SetBundle(nil)
+
+	tests := []struct {
+		locale, key string
+		params      Params
+		want        string
+	}{
+		{"en-US", "User.NotLoggedIn", nil, "Not logged in"},
+		{"en-GB", "User.NotLoggedIn", nil, "Not logged in"},
+		{"zh-TW", "User.NotLoggedIn", nil, "未登录"},
+		{"en-US", "Only", nil, "只有中文"},
+		{"en-US", "Missing.Key", nil, "Missing.Key"},
+		{"en-US", "User.Hello", Params{"name": "Tom"}, "Hello Tom, {unknown}"},
+		{"zh-CN", "User.Hello", Params{"name": 42}, "你好 42"},
+	}
+	for _, tt := range tests {
+		var got string
+		if tt.params != nil {
+			got = Translate(tt.locale, tt.key, tt.params)
+		} else {
+			got = Translate(tt.locale, tt.key)
+		}
+		if got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMessageFormat(t *testing.T) {
+	at := time.Date(2024, 3, 5, 14, 7, 0, 0, time.UTC)
+	tests := []struct {
+		locale, source string
+		params         Params
+		want           string
+	}{
+		{"en-US", "{count, plural, =0 {no items} one {# item} other {# items}}", Params{"count": 0}, "no items"},
+		{"en-US", "{count, plural, =0 {no items} one {# item} other {# items}}", Params{"count": 1}, "1 item"},
+		{"en-US", "{count, plural, =0 {no items} one {# item} other {# items}}", Params{"count": 1234}, "1,234 items"},
+		{"zh-CN", "{count, plural, one {# 项} other {# 项}}", Params{"count": 1}, "1 项"},
+		{"en-US", "{n, plural, offset:1 =1 {only you} one {you and # other} other {you and # others}}", Params{"n": 2}, "you and 1 other"},
+		{"en-US", "{n, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}", Params{"n": 22}, "22nd"},
+		{"en-US", "{g, select, male {he} female {she} other {they}}", Params{"g": "female"}, "she"},
+		{"en-US", "{g, select, male {he} female {she} other {they}}", nil, "they"},
+		{"en-US", "{ratio, number, percent} of {total, number}", Params{"ratio": 0.25, "total": 1234.5}, "25% of 1,234.5"},
+		{"en-US", "{at, date, medium} {at, time, short}", Params{"at": at}, "Mar 5, 2024 2:07 pm"},
+		{"zh-CN", "{at, date, long}", Params{"at": at}, "2024年3月5日"},
+		{"en-US", "it''s '{literal}' {name}", nil, "it's {literal} {name}"},
+	}
+	for _, tt := range tests {
+		m, err := parseMessage(tt.source)
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.source, err)
+			continue
+		}
+		if got := m.format(tt.locale, tt.params); got != tt.want {
+			t.Errorf("format(%s, %q) = %q, want %q", tt.locale, tt.source, got, tt.want)
+		}
+	}
+
+	for _, source := range []string{"{count, plural, one {#}}", "{name", "{x, unknown}", "text }"} {
+		if _, err := parseMessage(source); err == nil {
+			t.Errorf("parse %q: expected error", source)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package i18ntest 提供检查消息目录的测试辅助函数
+package i18ntest
+
+import (
+	"openapphub/pkg/i18n"
+	"slices"
+	"testing"
+)
+
+// AssertComplete 检查 dir 下的消息目录, 出现以下情况时测试失败:
+// 消息格式错误; 某种语言缺少其它语言定义的 key; 同一个 key 在不同语言中引用的参数不同;
+// src 不为空时, 代码中使用的 key 在任意语言中缺失. 没有被使用的 key 只记录日志
+func AssertComplete(t testing.TB, dir, src string) {
+	t.Helper()
+	b, err := i18n.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("load catalogs: %v", err)
+	}
+
+	// 所有语言的 key 的并集, 以及每个 key 第一次出现时引用的参数
+	defined := make(map[string]string)
+	for _, locale := range b.Locales() {
+		for _, key := range b.Keys(locale) {
+			if _, ok := defined[key]; !ok {
+				defined[key] = locale
+			}
+		}
+	}
+	for _, locale := range b.Locales() {
+		keys := b.Keys(locale)
+		for key, first := range defined {
+			if _, found := slices.BinarySearch(keys, key); !found {
+				t.Errorf("%s: missing key %s (defined in %s)", locale, key, first)
+				continue
+			}
+			if want, got := b.Arguments(first, key), b.Arguments(locale, key); !slices.Equal(want, got) {
+				t.Errorf("%s: %s uses arguments %v, %s uses %v", locale, key, got, first, want)
+			}
+		}
+	}
+
+	if src == "" {
+		return
+	}
+	usage, err := i18n.Extract(src)
+	if err != nil {
+		t.Fatalf("extract keys: %v", err)
+	}
+	for _, report := range usage.Check(b) {
+		for _, key := range report.Missing {
+			t.Errorf("%s: missing key %s used at %v", report.Locale, key, usage.Keys[key][0])
+		}
+		for _, key := range report.Unused {
+			t.Logf("%s: unused key %s", report.Locale, key)
+		}
+	}
+}
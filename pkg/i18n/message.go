@@ -0,0 +1,459 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+)
+
+// 消息使用 ICU MessageFormat 的常用子集:
+//
+//	{name}                                    参数
+//	{count, number} {ratio, number, percent}  数字, 样式为 integer、percent 或省略
+//	{at, date, short} {at, time}              日期和时间, 样式为 short、medium(默认)、long、full
+//	{count, plural, =0 {没有} one {# 项} other {# 项}}
+//	{place, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}
+//	{gender, select, male {他} female {她} other {TA}}
+//
+// plural 支持 offset:N, # 为减去 offset 后按语言格式化的数字; 单引号用于转义, '' 表示单引号本身, '{' 表示字面的 {
+
+// message 解析后的消息
+type message []node
+
+type node interface {
+	format(sb *strings.Builder, f *formatter)
+}
+
+// formatter 格式化一条消息时的上下文
+type formatter struct {
+	locale locales.Translator
+	params Params
+	number *float64 // 当前 plural 分支中 # 对应的数字
+}
+
+type textNode string
+
+func (n textNode) format(sb *strings.Builder, _ *formatter) {
+	sb.WriteString(string(n))
+}
+
+// argNode {name} 或 {name, number|date|time[, style]}
+type argNode struct {
+	name, kind, style string
+	source            string // 没有提供参数时原样输出
+}
+
+func (n argNode) format(sb *strings.Builder, f *formatter) {
+	value, ok := f.params[n.name]
+	if !ok {
+		sb.WriteString(n.source)
+		return
+	}
+	switch n.kind {
+	case "number":
+		num, ok := toFloat(value)
+		if !ok {
+			break
+		}
+		switch n.style {
+		case "integer":
+			sb.WriteString(f.locale.FmtNumber(math.Round(num), 0))
+		case "percent":
+			sb.WriteString(f.locale.FmtPercent(num*100, precision(num*100)))
+		default:
+			sb.WriteString(f.locale.FmtNumber(num, precision(num)))
+		}
+		return
+	case "date", "time":
+		t, ok := value.(time.Time)
+		if !ok {
+			break
+		}
+		sb.WriteString(formatTime(f.locale, n.kind, n.style, t))
+		return
+	}
+	sb.WriteString(fmt.Sprint(value))
+}
+
+// hashNode plural 分支中的 #
+type hashNode struct{}
+
+func (hashNode) format(sb *strings.Builder, f *formatter) {
+	if f.number == nil {
+		sb.WriteByte('#')
+		return
+	}
+	sb.WriteString(f.locale.FmtNumber(*f.number, precision(*f.number)))
+}
+
+// choiceNode plural、selectordinal 和 select
+type choiceNode struct {
+	name, kind string
+	offset     float64
+	cases      map[string]message
+}
+
+func (n choiceNode) format(sb *strings.Builder, f *formatter) {
+	value := f.params[n.name]
+	if n.kind == "select" {
+		selected, ok := n.cases[fmt.Sprint(value)]
+		if !ok || value == nil {
+			selected = n.cases["other"]
+		}
+		selected.write(sb, f)
+		return
+	}
+
+	num, ok := toFloat(value)
+	if !ok {
+		n.cases["other"].write(sb, f)
+		return
+	}
+	selected, ok := n.cases["="+strconv.FormatFloat(num, 'f', -1, 64)]
+	shown := num - n.offset
+	if !ok {
+		var rule locales.PluralRule
+		if n.kind == "selectordinal" {
+			rule = f.locale.OrdinalPluralRule(shown, precision(shown))
+		} else {
+			rule = f.locale.CardinalPluralRule(shown, precision(shown))
+		}
+		if selected, ok = n.cases[strings.ToLower(rule.String())]; !ok {
+			selected = n.cases["other"]
+		}
+	}
+	outer := f.number
+	f.number = &shown
+	selected.write(sb, f)
+	f.number = outer
+}
+
+func (m message) write(sb *strings.Builder, f *formatter) {
+	for _, n := range m {
+		n.format(sb, f)
+	}
+}
+
+// format 按语言格式化消息
+func (m message) format(locale string, params Params) string {
+	var sb strings.Builder
+	m.write(&sb, &formatter{locale: localeRules(locale), params: params})
+	return sb.String()
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]locales.Translator{
+		"en": en.New(),
+		"zh": zh.New(),
+	}
+)
+
+// RegisterLocaleRules 注册语言的复数规则和数字、日期格式, 默认支持 en 和 zh.
+// tag 可以是语言代码或完整的语言, 例如 RegisterLocaleRules("fr", fr.New())
+func RegisterLocaleRules(tag string, rule locales.Translator) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[Canonical(tag)] = rule
+}
+
+// localeRules 返回语言的格式规则, 依次查找完整语言和语言代码, 都没有时使用 en
+func localeRules(locale string) locales.Translator {
+	locale = Canonical(locale)
+	language, _, _ := strings.Cut(locale, "-")
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	for _, candidate := range []string{locale, language} {
+		if rule, ok := rules[candidate]; ok {
+			return rule
+		}
+	}
+	return rules["en"]
+}
+
+func formatTime(rule locales.Translator, kind, style string, t time.Time) string {
+	if kind == "time" {
+		switch style {
+		case "short":
+			return rule.FmtTimeShort(t)
+		case "long":
+			return rule.FmtTimeLong(t)
+		case "full":
+			return rule.FmtTimeFull(t)
+		}
+		return rule.FmtTimeMedium(t)
+	}
+	switch style {
+	case "short":
+		return rule.FmtDateShort(t)
+	case "long":
+		return rule.FmtDateLong(t)
+	case "full":
+		return rule.FmtDateFull(t)
+	}
+	return rule.FmtDateMedium(t)
+}
+
+// toFloat 把整数、浮点数和数字字符串转换为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// precision 返回小数位数, 最多 3 位
+func precision(num float64) uint64 {
+	s := strconv.FormatFloat(num, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return uint64(min(len(s)-i-1, 3))
+	}
+	return 0
+}
+
+// parseMessage 解析 ICU 消息
+func parseMessage(source string) (message, error) {
+	p := &messageParser{src: []rune(source)}
+	m, err := p.parse(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected }")
+	}
+	return m, nil
+}
+
+type messageParser struct {
+	src []rune
+	pos int
+}
+
+func (p *messageParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("message format: %s at offset %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+// parse 解析到消息结束或未匹配的 }, inPlural 为 true 时 # 表示数字
+func (p *messageParser) parse(inPlural bool) (message, error) {
+	var (
+		m    message
+		text strings.Builder
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			m = append(m, textNode(text.String()))
+			text.Reset()
+		}
+	}
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case r == '\'':
+			p.quoted(&text, inPlural)
+		case r == '{':
+			flush()
+			n, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, n)
+		case r == '}':
+			flush()
+			return m, nil
+		case r == '#' && inPlural:
+			flush()
+			m = append(m, hashNode{})
+			p.pos++
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+	flush()
+	return m, nil
+}
+
+// quoted 处理单引号: 连续两个单引号表示单引号本身, 引号后紧跟特殊字符时直到下一个单引号都是字面文本, 否则单引号本身是字面文本
+func (p *messageParser) quoted(text *strings.Builder, inPlural bool) {
+	p.pos++
+	if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+		text.WriteRune('\'')
+		p.pos++
+		return
+	}
+	if p.pos >= len(p.src) || !(p.src[p.pos] == '{' || p.src[p.pos] == '}' || (inPlural && p.src[p.pos] == '#')) {
+		text.WriteRune('\'')
+		return
+	}
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		p.pos++
+		if r != '\'' {
+			text.WriteRune(r)
+			continue
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+			text.WriteRune('\'')
+			p.pos++
+			continue
+		}
+		return
+	}
+}
+
+// argument 解析 { 开始的参数
+func (p *messageParser) argument() (node, error) {
+	start := p.pos
+	p.pos++ // {
+	name := p.word()
+	if name == "" {
+		return nil, p.errorf("missing argument name")
+	}
+	p.spaces()
+	if p.consume('}') {
+		return argNode{name: name, source: string(p.src[start:p.pos])}, nil
+	}
+	if !p.consume(',') {
+		return nil, p.errorf("expected , or } after argument %s", name)
+	}
+	p.spaces()
+	kind := p.word()
+	p.spaces()
+	switch kind {
+	case "number", "date", "time":
+		style := ""
+		if p.consume(',') {
+			end := p.pos
+			for end < len(p.src) && p.src[end] != '}' {
+				end++
+			}
+			style = strings.TrimSpace(string(p.src[p.pos:end]))
+			p.pos = end
+		}
+		if !p.consume('}') {
+			return nil, p.errorf("unterminated argument %s", name)
+		}
+		return argNode{name: name, kind: kind, style: style, source: string(p.src[start:p.pos])}, nil
+	case "plural", "selectordinal", "select":
+		if !p.consume(',') {
+			return nil, p.errorf("expected , after %s", kind)
+		}
+		return p.choice(name, kind)
+	}
+	return nil, p.errorf("unknown argument type %q", kind)
+}
+
+// choice 解析 plural、selectordinal 和 select 的分支
+func (p *messageParser) choice(name, kind string) (node, error) {
+	n := choiceNode{name: name, kind: kind, cases: make(map[string]message)}
+	for {
+		p.spaces()
+		if p.consume('}') {
+			break
+		}
+		selector := p.word()
+		if selector == "" {
+			return nil, p.errorf("expected selector in %s %s", kind, name)
+		}
+		if kind == "plural" && strings.HasPrefix(selector, "offset:") {
+			offset, err := strconv.ParseFloat(strings.TrimPrefix(selector, "offset:"), 64)
+			if err != nil {
+				return nil, p.errorf("invalid offset in %s", name)
+			}
+			n.offset = offset
+			continue
+		}
+		p.spaces()
+		if !p.consume('{') {
+			return nil, p.errorf("expected { after selector %s", selector)
+		}
+		m, err := p.parse(kind != "select")
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume('}') {
+			return nil, p.errorf("unterminated case %s in %s", selector, name)
+		}
+		n.cases[selector] = m
+	}
+	if _, ok := n.cases["other"]; !ok {
+		return nil, p.errorf("%s %s requires an other case", kind, name)
+	}
+	return n, nil
+}
+
+// word 读取到空白、逗号或括号为止
+func (p *messageParser) word() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if unicode.IsSpace(r) || r == ',' || r == '{' || r == '}' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *messageParser) spaces() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *messageParser) consume(r rune) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == r {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// arguments 返回消息引用的参数名
+func (m message) arguments(names map[string]struct{}) {
+	for _, n := range m {
+		switch n := n.(type) {
+		case argNode:
+			names[n.name] = struct{}{}
+		case choiceNode:
+			names[n.name] = struct{}{}
+			for _, c := range n.cases {
+				c.arguments(names)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package proxyproto 解析负载均衡器在 TCP 连接开头发送的 PROXY protocol 头(v1 文本格式和 v2 二进制格式),
+// 使 RemoteAddr 返回原始客户端地址
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidHeader PROXY protocol 头格式错误
+var ErrInvalidHeader = errors.New("proxyproto: invalid header")
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// v1MaxLength v1 头的最大长度, 包括结尾的 \r\n
+const v1MaxLength = 107
+
+// Listener 解析可信上游发送的 PROXY protocol 头
+type Listener struct {
+	net.Listener
+	// Trusted 判断连接是否来自可信的负载均衡器, 为 nil 时信任所有连接.
+	// 不可信的连接原样返回, 它们发送的 PROXY 头会被当作普通数据
+	Trusted func(addr net.Addr) bool
+	// Timeout 读取 PROXY 头的超时时间, 为 0 时不限制
+	Timeout time.Duration
+}
+
+// Accept 等待下一个连接
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if l.Trusted != nil && !l.Trusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), timeout: l.Timeout}, nil
+}
+
+// Conn 在第一次读取或调用 RemoteAddr 时解析 PROXY 头
+type Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+}
+
+// Read 读取 PROXY 头之后的数据
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr 返回 PROXY 头中的源地址, 没有源地址时返回连接的对端地址
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+	}
+
+	peek, err := c.reader.Peek(len(v1Prefix))
+	if err != nil {
+		c.err = err
+		return
+	}
+	if bytes.Equal(peek, v1Prefix) {
+		c.remoteAddr, c.err = readV1(c.reader)
+		return
+	}
+	peek, err = c.reader.Peek(len(v2Signature))
+	if err == nil && bytes.Equal(peek, v2Signature) {
+		c.remoteAddr, c.err = readV2(c.reader)
+		return
+	}
+	c.err = fmt.Errorf("%w: missing header", ErrInvalidHeader)
+}
+
+// readV1 解析 "PROXY TCP4 源地址 目标地址 源端口 目标端口\r\n"
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < v1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: v1 header too long", ErrInvalidHeader)
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 解析二进制头: 签名(12 字节) | 版本和命令 | 地址族和协议 | 长度(2 字节) | 地址
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(v2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	versionCommand, family := header[12], header[13]
+	length := binary.BigEndian.Uint16(header[14:])
+	if versionCommand>>4 != 2 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, versionCommand>>4)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	// LOCAL 命令是负载均衡器自身的健康检查, 使用连接的对端地址
+	if versionCommand&0x0F == 0 {
+		return nil, nil
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return nil, fmt.Errorf("%w: short IPv4 address", ErrInvalidHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return nil, fmt.Errorf("%w: short IPv6 address", ErrInvalidHeader)
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	// 其它地址族(UDP, Unix socket)不携带可用的客户端地址
+	return nil, nil
+}
@@ -0,0 +1,59 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+func newConn(t *testing.T, data []byte) *Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	go func() {
+		_, _ = client.Write(data)
+		_ = client.Close()
+	}()
+	t.Cleanup(func() { _ = server.Close() })
+	return &Conn{Conn: server, reader: bufio.NewReader(server)}
+}
+
+func TestV1Header(t *testing.T) {
+	conn := newConn(t, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET / HTTP/1.1\r\n"))
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:51234" {
+		t.Fatalf("RemoteAddr = %s", got)
+	}
+	body, _ := io.ReadAll(conn)
+	if string(body) != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestV2Header(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(v2Signature)
+	buf.Write([]byte{0x21, 0x11})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(12))
+	buf.Write([]byte{198, 51, 100, 9, 10, 0, 0, 1})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(40000))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(443))
+	buf.WriteString("ping")
+
+	conn := newConn(t, buf.Bytes())
+	if got := conn.RemoteAddr().String(); got != "198.51.100.9:40000" {
+		t.Fatalf("RemoteAddr = %s", got)
+	}
+	body, _ := io.ReadAll(conn)
+	if string(body) != "ping" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestMissingHeader(t *testing.T) {
+	conn := newConn(t, []byte("GET / HTTP/1.1\r\n"))
+	if _, err := conn.Read(make([]byte, 16)); err == nil {
+		t.Fatal("expected an error for a connection without PROXY header")
+	}
+}
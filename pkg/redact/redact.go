@@ -0,0 +1,217 @@
+// Package redact 按字段名和请求头规则隐藏日志中的敏感数据, 例如密码、令牌、Cookie 和数据库连接串.
+// 与 secrets.Mask 不同, 这里不需要知道敏感数据的值, 只看它出现在哪个字段里
+package redact
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"openapphub/pkg/secrets"
+)
+
+// Redacted 隐藏后显示的内容, 与配置中的密钥一致
+const Redacted = secrets.Redacted
+
+// DefaultFields 始终隐藏的字段, 字段名包含其中任一项(不区分大小写)时隐藏
+var DefaultFields = []string{"password", "token", "secret", "dsn", "authorization", "cookie"}
+
+// DefaultHeaders 始终隐藏的请求头和响应头
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Admin-Token"}
+
+// Rules 在默认规则之外需要隐藏的字段和请求头
+type Rules struct {
+	// Fields 字段名包含其中任一项(不区分大小写)时隐藏, 适用于 JSON、表单、查询参数、日志字段和 SQL 列
+	Fields []string
+	// Headers 请求头名, 不区分大小写
+	Headers []string
+}
+
+// Redactor 按规则隐藏敏感数据, 可以并发使用
+type Redactor struct {
+	fields  []string
+	headers map[string]struct{}
+}
+
+var current atomic.Pointer[Redactor]
+
+func init() {
+	current.Store(New(Rules{}))
+}
+
+// New 按默认规则和 rules 创建 Redactor
+func New(rules Rules) *Redactor {
+	r := &Redactor{headers: make(map[string]struct{})}
+	for _, field := range append(append([]string(nil), DefaultFields...), rules.Fields...) {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			r.fields = append(r.fields, field)
+		}
+	}
+	for _, header := range append(append([]string(nil), DefaultHeaders...), rules.Headers...) {
+		if header = strings.TrimSpace(header); header != "" {
+			r.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+		}
+	}
+	return r
+}
+
+// Configure 替换全局使用的规则
+func Configure(rules Rules) {
+	current.Store(New(rules))
+}
+
+// Current 返回全局使用的 Redactor
+func Current() *Redactor {
+	return current.Load()
+}
+
+// Field 判断字段是否需要隐藏
+func (r *Redactor) Field(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range r.fields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header 判断请求头是否需要隐藏
+func (r *Redactor) Header(name string) bool {
+	_, ok := r.headers[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+// Headers 返回隐藏了敏感请求头的副本
+func (r *Redactor) Headers(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if r.Header(name) {
+			values = []string{Redacted}
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+// Query 隐藏查询参数或表单中敏感字段的值, 保持参数顺序
+func (r *Redactor) Query(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, _, ok := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); ok && err == nil && r.Field(name) {
+			pairs[i] = key + "=" + Redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// JSON 隐藏 JSON 中敏感字段的值, 包括嵌套的对象和数组. data 不是合法的 JSON 时返回 false
+func (r *Redactor) JSON(data []byte) ([]byte, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	redacted, err := json.Marshal(r.value(value))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+func (r *Redactor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if r.Field(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = r.value(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = r.value(val)
+		}
+	}
+	return value
+}
+
+// Body 返回可以写入日志的请求体: JSON 和表单隐藏敏感字段, 其它内容只记录长度
+func (r *Redactor) Body(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	if redacted, ok := r.JSON(data); ok {
+		return string(redacted)
+	}
+	if text := string(data); !strings.ContainsAny(text, " \r\n\t") && strings.Contains(text, "=") {
+		if _, err := url.ParseQuery(text); err == nil {
+			return r.Query(text)
+		}
+	}
+	return fmt.Sprintf("[%d bytes]", len(data))
+}
+
+// urlCredentials 匹配 scheme://user:password@ 中的密码
+var urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/@\s]*:)[^@\s]*@`)
+
+// DSN 隐藏连接串中的密码, 支持 URL 格式和 MySQL 格式
+func (r *Redactor) DSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		return urlCredentials.ReplaceAllString(dsn, "${1}"+Redacted+"@")
+	}
+	// go-sql-driver/mysql 格式 user:password@protocol(address)/dbname, 与驱动一样以最后一个 / 之前的最后一个 @ 分隔, 密码中可以有 @
+	slash := strings.LastIndexByte(dsn, '/')
+	if slash < 0 {
+		return dsn
+	}
+	at := strings.LastIndexByte(dsn[:slash], '@')
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.IndexByte(dsn[:at], ':')
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + Redacted + dsn[at:]
+}
+
+// Request 隐藏 httputil.DumpRequest 输出中的敏感请求头和查询参数
+func (r *Redactor) Request(dump string) string {
+	var b strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case first:
+			// 请求行, 例如 GET /path?token=... HTTP/1.1
+			if method, rest, ok := strings.Cut(line, " "); ok {
+				target, proto, _ := strings.Cut(rest, " ")
+				if path, query, ok := strings.Cut(target, "?"); ok {
+					line = method + " " + path + "?" + r.Query(query) + " " + proto
+				}
+			}
+			first = false
+		default:
+			if name, _, ok := strings.Cut(line, ":"); ok && r.Header(name) {
+				line = name + ": " + Redacted
+			}
+		}
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
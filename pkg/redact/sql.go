@@ -0,0 +1,85 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sqlInsert 匹配 INSERT INTO table (columns) VALUES
+var sqlInsert = regexp.MustCompile("(?is)^\\s*(?:insert|replace)\\s+(?:ignore\\s+)?into\\s+[`\"\\w.]+\\s*\\(([^)]*)\\)\\s*values")
+
+// sqlCompare 匹配占位符前的列名和比较运算符, 例如 `password_digest` = 、token IN (?,
+var sqlCompare = regexp.MustCompile("(?is)([`\"\\w.]+)\\s*(?:=|<>|!=|<=|>=|<|>|\\slike|\\sin\\s*\\()\\s*(?:\\?\\s*,\\s*)*$")
+
+// SQLParams 隐藏绑定到敏感列的参数, 用于在日志中输出完整的 SQL.
+// 支持 INSERT 的列列表和 列 运算符 ? 形式的条件与赋值; 占位符数量与参数不一致时隐藏全部参数
+func (r *Redactor) SQLParams(sql string, params []interface{}) []interface{} {
+	if len(params) == 0 {
+		return params
+	}
+	placeholders := sqlPlaceholders(sql)
+	redacted := make([]interface{}, len(params))
+	if len(placeholders) != len(params) {
+		for i := range redacted {
+			redacted[i] = Redacted
+		}
+		return redacted
+	}
+
+	// INSERT 的参数按列列表循环对应, 支持一次插入多行
+	var columns []string
+	valuesEnd := -1
+	if m := sqlInsert.FindStringSubmatchIndex(sql); m != nil {
+		for _, column := range strings.Split(sql[m[2]:m[3]], ",") {
+			columns = append(columns, column)
+		}
+		valuesEnd = m[1]
+	}
+
+	inserted := 0
+	for i, pos := range placeholders {
+		var column string
+		if columns != nil && pos >= valuesEnd {
+			column = columns[inserted%len(columns)]
+			inserted++
+		} else if m := sqlCompare.FindStringSubmatch(sql[:pos]); m != nil {
+			column = m[1]
+		}
+		redacted[i] = params[i]
+		if column != "" && r.Field(sqlIdentifier(column)) {
+			redacted[i] = Redacted
+		}
+	}
+	return redacted
+}
+
+// sqlPlaceholders 返回引号外的 ? 占位符位置
+func sqlPlaceholders(sql string) []int {
+	var positions []int
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// sqlIdentifier 去掉表名和引号, 例如 `users`.`password_digest` -> password_digest
+func sqlIdentifier(column string) string {
+	column = strings.TrimSpace(column)
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.Trim(column, "`\"")
+}
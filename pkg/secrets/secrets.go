@@ -0,0 +1,229 @@
+// Package secrets 解析配置中的密钥引用:
+// 用本地密钥加密的值 ENC[...] 和外部密钥服务中的值 ref+<provider>://<path>#<key>.
+// 解析出的密钥会被记录下来, 日志输出前用 Mask 隐藏
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// KeySize 本地加密密钥的长度
+const KeySize = 32
+
+// Redacted 隐藏密钥后显示的内容
+const Redacted = "******"
+
+// minMaskLength 太短的值不做替换, 避免把普通单词也替换掉
+const minMaskLength = 4
+
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+	referencePrefix = "ref+"
+	nonceSize       = 24
+)
+
+var (
+	// ErrNoKey 没有配置本地加密密钥
+	ErrNoKey = errors.New("secrets: no encryption key configured")
+	// ErrDecrypt 密文格式错误或密钥不匹配
+	ErrDecrypt = errors.New("secrets: unable to decrypt value")
+	// ErrUnknownProvider 引用了没有注册的密钥服务
+	ErrUnknownProvider = errors.New("secrets: unknown provider")
+)
+
+// Provider 外部密钥服务, 例如 Vault
+type Provider interface {
+	// Get 返回 path 下名为 key 的密钥
+	Get(ctx context.Context, path, key string) (string, error)
+}
+
+var (
+	mu        sync.RWMutex
+	key       *[KeySize]byte
+	providers = make(map[string]Provider)
+	known     = make(map[string]struct{})
+)
+
+// SetKey 设置解密 ENC[...] 使用的本地密钥, encoded 为 base64 编码的 32 字节, 为空时清除密钥
+func SetKey(encoded string) error {
+	if encoded == "" {
+		mu.Lock()
+		key = nil
+		mu.Unlock()
+		return nil
+	}
+	k, err := decodeKey(encoded)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	key = k
+	mu.Unlock()
+	return nil
+}
+
+// RegisterProvider 注册外部密钥服务, 引用格式为 ref+<name>://<path>#<key>
+func RegisterProvider(name string, provider Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		delete(providers, name)
+		return
+	}
+	providers[name] = provider
+}
+
+// IsReference 判断值是否需要解析
+func IsReference(value string) bool {
+	return isEncrypted(value) || strings.HasPrefix(value, referencePrefix)
+}
+
+// Resolve 解析密钥引用, 普通值原样返回. 返回的错误不包含密钥内容
+func Resolve(ctx context.Context, value string) (string, error) {
+	var (
+		resolved string
+		err      error
+	)
+	switch {
+	case isEncrypted(value):
+		mu.RLock()
+		k := key
+		mu.RUnlock()
+		if k == nil {
+			return "", ErrNoKey
+		}
+		resolved, err = decrypt(k, value)
+	case strings.HasPrefix(value, referencePrefix):
+		resolved, err = resolveReference(ctx, strings.TrimPrefix(value, referencePrefix))
+	default:
+		resolved = value
+	}
+	if err != nil {
+		return "", err
+	}
+	Track(resolved)
+	return resolved, nil
+}
+
+// resolveReference 解析 <provider>://<path>#<key>
+func resolveReference(ctx context.Context, ref string) (string, error) {
+	name, rest, ok := strings.Cut(ref, "://")
+	if !ok {
+		return "", fmt.Errorf("secrets: invalid reference, expected ref+<provider>://<path>#<key>")
+	}
+	path, field, ok := strings.Cut(rest, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("secrets: reference to %s must include a path and #key", name)
+	}
+	mu.RLock()
+	provider := providers[name]
+	mu.RUnlock()
+	if provider == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider.Get(ctx, path, field)
+}
+
+// ReadFile 读取挂载的密钥文件, 去掉末尾的换行
+func ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// GenerateKey 生成 base64 编码的本地加密密钥
+func GenerateKey() (string, error) {
+	var k [KeySize]byte
+	if _, err := rand.Read(k[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(k[:]), nil
+}
+
+// Encrypt 用本地密钥加密, 返回可以直接写进配置的 ENC[...]
+func Encrypt(encodedKey, plaintext string) (string, error) {
+	k, err := decodeKey(encodedKey)
+	if err != nil {
+		return "", err
+	}
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	sealed := secretbox.Seal(nonce[:], []byte(plaintext), &nonce, k)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+func decrypt(k *[KeySize]byte, value string) (string, error) {
+	encoded := strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix)
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < nonceSize+secretbox.Overhead {
+		return "", ErrDecrypt
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[:nonceSize])
+	plaintext, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, k)
+	if !ok {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+func decodeKey(encoded string) (*[KeySize]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(raw) != KeySize {
+		return nil, fmt.Errorf("secrets: key must be %d bytes encoded as base64", KeySize)
+	}
+	var k [KeySize]byte
+	copy(k[:], raw)
+	return &k, nil
+}
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// Track 记录需要在日志中隐藏的值
+func Track(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minMaskLength {
+			known[value] = struct{}{}
+		}
+	}
+}
+
+// Mask 把文本中出现的已知密钥替换为 Redacted
+func Mask(text string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(known) == 0 || text == "" {
+		return text
+	}
+	// 先替换较长的值, 避免一个密钥是另一个密钥的一部分时只替换了一半
+	values := make([]string, 0, len(known))
+	for value := range known {
+		if strings.Contains(text, value) {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, Redacted)
+	}
+	return text
+}
@@ -0,0 +1,89 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEncryptedValues(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := Encrypt(key, "jwt-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetKey("")
+
+	if _, err := Resolve(context.Background(), value); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+	other, _ := GenerateKey()
+	if err := SetKey(other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve(context.Background(), value); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt with the wrong key, got %v", err)
+	}
+	if err := SetKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Resolve(context.Background(), value); err != nil || got != "jwt-secret" {
+		t.Fatalf("Resolve = %q, %v", got, err)
+	}
+	if got := Mask("token=jwt-secret"); got != "token="+Redacted {
+		t.Errorf("Mask = %q", got)
+	}
+	if got, _ := Resolve(context.Background(), "plain"); got != "plain" {
+		t.Errorf("plain values should be returned unchanged, got %q", got)
+	}
+	if err := SetKey("c2hvcnQ="); err == nil {
+		t.Error("short keys should be rejected")
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			w.Write([]byte(`{"data":{"data":{"jwt_secret":"from-kv2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/app":
+			w.Write([]byte(`{"data":{"dsn":"from-kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	RegisterProvider("vault", NewVault(server.URL, "token", ""))
+	defer RegisterProvider("vault", nil)
+
+	ctx := context.Background()
+	for ref, want := range map[string]string{
+		"ref+vault://secret/data/app#jwt_secret": "from-kv2",
+		"ref+vault://kv/app#dsn":                 "from-kv1",
+	} {
+		if got, err := Resolve(ctx, ref); err != nil || got != want {
+			t.Errorf("Resolve(%s) = %q, %v, want %q", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{
+		"ref+vault://secret/data/app#missing",
+		"ref+vault://secret/data/other#key",
+		"ref+vault://secret/data/app",
+	} {
+		if _, err := Resolve(ctx, ref); err == nil {
+			t.Errorf("Resolve(%s) should fail", ref)
+		}
+	}
+	if _, err := Resolve(ctx, "ref+aws://app#key"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
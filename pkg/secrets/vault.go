@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vault 兼容 HashiCorp Vault KV 引擎(v1 和 v2)HTTP 接口的密钥服务.
+// path 为完整的接口路径, 例如 KV v2 的 secret/data/openapphub
+type Vault struct {
+	Addr      string // 例如 https://vault.example.com:8200
+	Token     string
+	Namespace string // Vault Enterprise 命名空间, 可以为空
+	Client    *http.Client
+}
+
+// NewVault 创建 Vault 密钥服务
+func NewVault(addr, token, namespace string) *Vault {
+	return &Vault{
+		Addr:      strings.TrimRight(addr, "/"),
+		Token:     token,
+		Namespace: namespace,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// vaultResponse KV v1 的数据在 data 中, KV v2 的数据在 data.data 中
+type vaultResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+func (v *Vault) Get(ctx context.Context, path, key string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: %s: unexpected status %d", path, resp.StatusCode)
+	}
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: %s: %w", path, err)
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault: %s: key %s not found", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,66 @@
+package serializer
+
+import (
+	"encoding/json"
+	"openapphub/internal/model"
+	"time"
+)
+
+// AuditEvent 审计记录序列化器
+type AuditEvent struct {
+	ID         uint              `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Action     string            `json:"action"`
+	ActorID    uint              `json:"actor_id,omitempty"`
+	Actor      string            `json:"actor,omitempty"`
+	TargetType string            `json:"target_type,omitempty"`
+	TargetID   string            `json:"target_id,omitempty"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"user_agent"`
+	Outcome    string            `json:"outcome"`
+	Reason     string            `json:"reason,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	Hash       string            `json:"hash"`
+}
+
+// BuildAuditEvent 序列化审计记录
+func BuildAuditEvent(event model.AuditEvent) AuditEvent {
+	e := AuditEvent{
+		ID:         event.ID,
+		OccurredAt: event.OccurredAt.UTC(),
+		Action:     event.Action,
+		ActorID:    event.ActorID,
+		Actor:      event.Actor,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		IP:         event.IP,
+		UserAgent:  event.UserAgent,
+		Outcome:    event.Outcome,
+		Reason:     event.Reason,
+		RequestID:  event.RequestID,
+		Hash:       event.Hash,
+	}
+	if event.Details != "" {
+		_ = json.Unmarshal([]byte(event.Details), &e.Details)
+	}
+	return e
+}
+
+// BuildAuditEvents 序列化审计记录列表, next_before 为下一页的 before 参数, 没有更多记录时为 0
+func BuildAuditEvents(events []model.AuditEvent, limit int) Response {
+	items := make([]AuditEvent, 0, len(events))
+	for _, event := range events {
+		items = append(items, BuildAuditEvent(event))
+	}
+	var next uint
+	if len(events) == limit && limit > 0 {
+		next = events[len(events)-1].ID
+	}
+	return Response{
+		Data: map[string]interface{}{
+			"events":      items,
+			"next_before": next,
+		},
+	}
+}
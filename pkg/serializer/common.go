@@ -1,7 +1,12 @@
 package serializer
 
 import (
+	"context"
 	"fmt"
+	"time"
+
+	"openapphub/internal/util"
+	"openapphub/pkg/i18n"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +20,22 @@ type Response struct {
 	Token interface{} `json:"token,omitempty"`
 }
 
+// RateLimitInfo 触发限流时在 Data 中返回, 客户端据此退避重试
+type RateLimitInfo struct {
+	Policy     string    `json:"policy"`      // 限流策略名称
+	Limit      string    `json:"limit"`       // 触发的限额, 例如 "100-H"
+	Reset      time.Time `json:"reset"`       // 限额重置时间
+	RetryAfter int64     `json:"retry_after"` // 距离重置的秒数, 与 Retry-After 响应头一致
+}
+
+// FieldError 参数校验失败时在 Data 中返回, 每个不合法的字段一项
+type FieldError struct {
+	Field   string `json:"field"`           // 字段在请求中的路径, 例如 user_name、items[0].name
+	Tag     string `json:"tag"`             // 校验规则, 例如 required、min
+	Param   string `json:"param,omitempty"` // 校验规则的参数, 例如 min=5 中的 5
+	Message string `json:"message"`         // 按请求语言翻译的错误消息
+}
+
 // TrackedErrorResponse 有追踪信息的错误响应
 type TrackedErrorResponse struct {
 	Response
@@ -40,13 +61,23 @@ const (
 	CodeRateLimitExceeded = 40002
 	// CodeInternalServerError 内部服务器错误
 	CodeInternalServerError = 50000
+	// CodeServerBusy 服务器繁忙, 请求被限流丢弃
+	CodeServerBusy = 50003
 )
 
+// Track 为服务器端错误附加请求 ID, 用户反馈问题时提供 track_id 即可在日志中找到对应的请求
+func Track(ctx context.Context, res Response) TrackedErrorResponse {
+	return TrackedErrorResponse{
+		Response: res,
+		TrackID:  util.RequestID(ctx),
+	}
+}
+
 // CheckLogin 检查登录
-func CheckLogin() Response {
+func CheckLogin(ctx context.Context) Response {
 	return Response{
 		Code: CodeCheckLogin,
-		Msg:  "未登录",
+		Msg:  i18n.T(ctx, "User.NotLoggedIn"),
 	}
 }
 
@@ -63,18 +94,18 @@ func Err(errCode int, msg string, err error) Response {
 	return res
 }
 
-// DBErr 数据库操作失败
+// DBErr 数据库操作失败, msg 为空时使用默认语言的提示
 func DBErr(msg string, err error) Response {
 	if msg == "" {
-		msg = "数据库操作失败"
+		msg = i18n.Translate(i18n.DefaultLocale, "Common.DBErr")
 	}
 	return Err(CodeDBError, msg, err)
 }
 
-// ParamErr 各种参数错误
+// ParamErr 各种参数错误, msg 为空时使用默认语言的提示
 func ParamErr(msg string, err error) Response {
 	if msg == "" {
-		msg = "参数错误"
+		msg = i18n.Translate(i18n.DefaultLocale, "Common.ParamErr")
 	}
 	return Err(CodeParamErr, msg, err)
 }
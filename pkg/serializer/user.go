@@ -13,6 +13,7 @@ type User struct {
 	Nickname  string `json:"nickname"`
 	Status    string `json:"status"`
 	Avatar    string `json:"avatar"`
+	Locale    string `json:"locale"`
 	CreatedAt int64  `json:"created_at"`
 }
 
@@ -24,6 +25,7 @@ func BuildUser(user model.User) User {
 		Nickname:  user.Nickname,
 		Status:    user.Status,
 		Avatar:    user.Avatar,
+		Locale:    user.Locale,
 		CreatedAt: user.CreatedAt.Unix(),
 	}
 }
@@ -2,14 +2,14 @@ package test
 
 import (
 	"openapphub/internal/config"
+	"openapphub/internal/middleware"
 	"openapphub/internal/model"
 	"openapphub/internal/server"
 	"openapphub/internal/util"
 	"openapphub/pkg/cache"
-	"os"
+	"openapphub/pkg/i18n"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 var (
@@ -18,25 +18,31 @@ var (
 
 func init() {
 	// 从配置文件读取配置
-	confInit()
+	conf := confInit()
 	// API
-	s = server.NewRouter()
+	s = server.NewRouter(conf)
 }
 
 // Init 初始化配置项
-func confInit() {
-	// 从本地读取环境变量
-	godotenv.Load()
+func confInit() *config.Config {
+	conf, err := config.Load()
+	if err != nil {
+		panic(err)
+	}
 
-	// 设置日志级别
-	util.BuildLogger(os.Getenv("LOG_LEVEL"))
+	// 按配置初始化日志, 日志级别来自 LOG_LEVEL
+	if err := middleware.InitLogger(conf.LogOptions()); err != nil {
+		panic(err)
+	}
+	util.BuildLogger(middleware.GetZapLogger())
 
 	// 读取翻译文件
-	if err := config.LoadLocales("../config/locales/zh-cn.yaml"); err != nil {
-		util.Log().Panic("翻译文件加载失败", err)
+	if err := i18n.LoadDir("../internal/config/locales"); err != nil {
+		util.Log().Panic("翻译文件加载失败: %v", err)
 	}
 
 	// 连接数据库
-	model.Database(os.Getenv("MYSQL_DSN"))
-	cache.Redis()
+	model.Database(conf.Database.DSN, model.NewGormLogger(middleware.GetZapLogger().Named("gorm"), 0))
+	cache.Init(conf.CacheStore())
+	return conf
 }